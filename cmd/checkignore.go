@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"tmpltr/internal/ignore"
+	"tmpltr/internal/storage"
+)
+
+// Sources of command-line patterns in explanations
+const (
+	ignoreFilesSource    = "--ignore-files"
+	includeSource        = "--include"
+	maxFileSizeSource    = "--max-file-size"
+	excludeBinarySource  = "--exclude-binary"
+	contentsIgnoreSource = "--contents-ignore"
+)
+
+var (
+	checkIgnoreFiles     []string
+	checkIncludeFiles    []string
+	checkIgnoreGitIgnore bool
+	checkNoDefaults      bool
+	checkPresets         []string
+	checkAutoPreset      bool
+	checkMaxFileSize     string
+	checkExcludeBinary   bool
+	checkContentsIgnore  []string
+)
+
+// checkIgnoreCmd represents the check-ignore command
+var checkIgnoreCmd = &cobra.Command{
+	Use:   "check-ignore <directory> [paths...]",
+	Short: "Explain why paths are or are not ignored by make",
+	Long: `Show for each path, relative to the directory, whether make would capture it,
+the pattern that decided it and where the pattern comes from: a built-in
+default, a preset, a line of a .tmpltrignore file or --ignore-files. Files
+matching no include pattern, if there are any, are reported as not included,
+and files skipped by a size or type rule as skipped. Files captured as
+structure only by a contents-ignore pattern are reported as such.
+
+Without paths, every path in the directory matched by a pattern is listed,
+as make --explain-ignores would.
+
+Examples:
+  tmpltr check-ignore ./my-project build/output.bin .env
+  tmpltr check-ignore ./my-project --ignore-files="*.bak" --gitignore
+  tmpltr check-ignore ./my-project --auto-preset`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runCheckIgnore,
+}
+
+func init() {
+	checkIgnoreCmd.Flags().StringSliceVar(&checkIgnoreFiles, "ignore-files", []string{}, "Comma-separated list of files/patterns to ignore, as for make")
+	checkIgnoreCmd.Flags().StringSliceVar(&checkIncludeFiles, "include", []string{}, "Comma-separated list of include patterns, as for make")
+	checkIgnoreCmd.Flags().BoolVar(&checkIgnoreGitIgnore, "gitignore", false, "Also honor .gitignore files and .git/info/exclude (default from config)")
+	checkIgnoreCmd.Flags().BoolVar(&checkNoDefaults, "no-default-ignores", false, "Do not apply the built-in default ignore patterns, as for make")
+	checkIgnoreCmd.Flags().StringSliceVar(&checkPresets, "preset", []string{}, "Comma-separated list of ecosystem presets, as for make")
+	checkIgnoreCmd.Flags().BoolVar(&checkAutoPreset, "auto-preset", false, "Add the presets of the ecosystems detected in the directory, as for make")
+	checkIgnoreCmd.Flags().StringVar(&checkMaxFileSize, "max-file-size", "", "Skip files larger than this size, as for make")
+	checkIgnoreCmd.Flags().BoolVar(&checkExcludeBinary, "exclude-binary", false, "Skip binary files, as for make")
+	checkIgnoreCmd.Flags().StringSliceVar(&checkContentsIgnore, "contents-ignore", []string{}, "Comma-separated list of patterns of files to save as structure only, as for make")
+	checkIgnoreCmd.RegisterFlagCompletionFunc("preset", completePresets)
+
+	// Add completion for the directory argument
+	checkIgnoreCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		}
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+}
+
+// runCheckIgnore executes the check-ignore command logic
+func runCheckIgnore(cmd *cobra.Command, args []string) error {
+	dir := args[0]
+	if err := validateTargetDirectory(dir); err != nil {
+		return err
+	}
+
+	// Initialize storage
+	storage, err := storage.NewStorage("")
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	gitIgnore, err := resolveGitIgnore(cmd, checkIgnoreGitIgnore, storage)
+	if err != nil {
+		return err
+	}
+
+	opts := ignoreOptions{
+		patterns:   checkIgnoreFiles,
+		includes:   checkIncludeFiles,
+		gitIgnore:  gitIgnore,
+		noDefaults: checkNoDefaults,
+		presets:    checkPresets,
+		autoPreset: checkAutoPreset,
+		maxSize:    checkMaxFileSize,
+		noBinary:   checkExcludeBinary,
+		contents:   checkContentsIgnore,
+	}
+	ignoreRules, err := setupIgnoreRules(dir, opts)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if len(args) == 1 {
+		err = explainDirectory(w, dir, ignoreRules)
+	} else {
+		err = explainPaths(w, dir, args[1:], ignoreRules)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// explainPaths explains the decisions for the given paths relative to dir,
+// including those inherited from ignored parent directories
+func explainPaths(w *tabwriter.Writer, dir string, paths []string, ignoreRules *ignore.IgnoreRules) error {
+	for _, p := range paths {
+		relPath := filepath.ToSlash(filepath.Clean(p))
+		if relPath == "." || relPath == ".." || strings.HasPrefix(relPath, "../") || filepath.IsAbs(p) {
+			return fmt.Errorf("path must be relative to the directory and inside it: %s", p)
+		}
+
+		// Paths need not exist; a trailing slash marks a directory that does not
+		isDir := strings.HasSuffix(p, "/")
+		if info, err := os.Stat(filepath.Join(dir, p)); err == nil {
+			isDir = info.IsDir()
+		}
+
+		filePath := filepath.Join(dir, filepath.FromSlash(relPath))
+		decision, err := ignoreRules.ExplainPath(filePath, isDir)
+		if err != nil {
+			return err
+		}
+		description, _, err := describeCapture(filePath, isDir, decision, ignoreRules)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\n", displayPath(relPath, isDir), description)
+	}
+	return nil
+}
+
+// explainDirectory walks a directory as make does and explains the decision for
+// every path matched by a pattern
+func explainDirectory(w *tabwriter.Writer, dir string, ignoreRules *ignore.IgnoreRules) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("error walking directory: %w", err)
+		}
+
+		decision, err := ignoreRules.Explain(path, d.IsDir())
+		if err != nil {
+			return err
+		}
+		description, matched, err := describeCapture(path, d.IsDir(), decision, ignoreRules)
+		if err != nil {
+			return err
+		}
+
+		if matched {
+			relPath, err := filepath.Rel(dir, path)
+			if err != nil {
+				return fmt.Errorf("failed to calculate relative path: %w", err)
+			}
+			fmt.Fprintf(w, "%s\t%s\n", displayPath(filepath.ToSlash(relPath), d.IsDir()), description)
+		}
+
+		if d.IsDir() && decision.Ignored {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// describeCapture completes the decision of the patterns for a kept file with the
+// size, type and contents-ignore rules and describes it, reporting whether any
+// pattern or rule matched the path
+func describeCapture(filePath string, isDir bool, decision ignore.Decision, ignoreRules *ignore.IgnoreRules) (string, bool, error) {
+	if decision.Ignored || isDir {
+		return describeIgnoreDecision(decision), decision.Pattern != nil, nil
+	}
+
+	decision, err := explainExistingFile(filePath, decision, ignoreRules)
+	if err != nil {
+		return "", false, err
+	}
+	if decision.Ignored {
+		return describeIgnoreDecision(decision), true, nil
+	}
+
+	description := describeIgnoreDecision(decision)
+	structureOnly, pattern, err := ignoreRules.ExplainContents(filePath)
+	if err != nil {
+		return "", false, err
+	}
+	if pattern != nil {
+		description += "; " + describeContentsDecision(structureOnly, pattern)
+	}
+	return description, decision.Pattern != nil || pattern != nil, nil
+}
+
+// explainExistingFile applies the size and type rules to a file the patterns keep,
+// returning the pattern decision unchanged if the file does not exist or no rule skips it
+func explainExistingFile(filePath string, decision ignore.Decision, ignoreRules *ignore.IgnoreRules) (ignore.Decision, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return decision, nil
+	}
+
+	fileDecision, err := ignoreRules.ExplainFile(filePath, info.Size(), func() ([]byte, error) {
+		return ignore.ReadHead(filePath)
+	})
+	if err != nil || !fileDecision.Ignored {
+		return decision, err
+	}
+	return fileDecision, nil
+}
+
+// printIgnoreDecision prints the decision for a path for make --explain-ignores
+func printIgnoreDecision(relPath string, isDir bool, decision ignore.Decision) {
+	fmt.Printf("  %s: %s\n", displayPath(relPath, isDir), describeIgnoreDecision(decision))
+}
+
+// describeIgnoreDecision describes an ignore decision and the pattern behind it
+func describeIgnoreDecision(decision ignore.Decision) string {
+	switch {
+	case decision.Predicate != nil:
+		return fmt.Sprintf("skipped by %s: %s", decision.Predicate.Source(), decision.Predicate.Text)
+	case decision.NotIncluded && decision.Pattern == nil:
+		return "not included (no include pattern matches)"
+	case decision.NotIncluded:
+		return fmt.Sprintf("not included, excluded by %s: %s", decision.Pattern.Source(), decision.Pattern.Text)
+	case decision.Pattern == nil:
+		return "included (no matching pattern)"
+	case decision.Parent != "":
+		return fmt.Sprintf("ignored (parent directory %s/ ignored by %s: %s)",
+			decision.Parent, decision.Pattern.Source(), decision.Pattern.Text)
+	case decision.Ignored:
+		return fmt.Sprintf("ignored by %s: %s", decision.Pattern.Source(), decision.Pattern.Text)
+	default:
+		return fmt.Sprintf("included by %s: %s", decision.Pattern.Source(), decision.Pattern.Text)
+	}
+}
+
+// describeContentsDecision describes whether a file is captured as structure
+// only and the contents-ignore pattern behind it
+func describeContentsDecision(structureOnly bool, pattern *ignore.Pattern) string {
+	if structureOnly {
+		return fmt.Sprintf("structure only by %s: contents-ignore %s", pattern.Source(), pattern.Text)
+	}
+	return fmt.Sprintf("contents kept by %s: contents-ignore %s", pattern.Source(), pattern.Text)
+}
+
+// displayPath formats a slash-separated path for display, marking directories
+func displayPath(relPath string, isDir bool) string {
+	if isDir {
+		return relPath + "/"
+	}
+	return relPath
+}
@@ -91,17 +91,40 @@ export extern "tmpltr" [
 ]
 
 export extern "tmpltr make" [
-    path: string                  # Target directory path
+    path?: string                 # Target directory path, or "-" for a tar stream on stdin
     --name(-n): string           # Template name (required)
+    --from-archive: string       # Create the template from a tar, tar.gz or zip archive
+    --git: string                # Create the template from a commit of a git repository
+    --ref: string                # Commit, branch or tag to capture with --git
+    --gitignore                  # Also honor .gitignore files and .git/info/exclude
+    --explain-ignores            # Print the pattern deciding each ignored path
+    --include: list<string>      # Only capture files matching these patterns
+    --no-default-ignores         # Do not apply the built-in default ignore patterns
+    --preset: list<string>       # Ecosystem presets to ignore the build output of
+    --auto-preset                # Add the presets of the detected ecosystems
+    --max-file-size: string      # Skip files larger than this size
+    --exclude-binary             # Skip binary files
     --ignore-contents            # Only save file structure, ignore contents
+    --contents-ignore: list<string> # Files/patterns to save as structure only
+    --placeholder: string        # Placeholder for structure-only files: empty, header, skeleton or sized
+    --placeholder-lines: int     # Number of lines kept by --placeholder=header
+    --allow-secrets              # Capture files even if they appear to contain secrets
+    --redact-secrets             # Replace secrets by variables supplied on restore
     --ignore-files: list<string> # Files/patterns to ignore
     --no-compression             # Disable compression
+    --jobs(-j): int              # Number of files to process concurrently
+    --no-cache                   # Hash every file, ignoring the hash cache
+    --update                     # Replace the contents of an existing template
+    --tag: string                # Tag for the created template version
     --help(-h)                   # Show help
 ]
 
 export extern "tmpltr restore" [
-    --name(-n): string           # Template name to restore (required)
+    --name(-n): string           # Template name to restore
     --output(-o): string         # Output directory path (required)
+    --from: string               # Exported bundle or template directory to restore from
+    --format: string             # Output format: dir, tar or tar.gz
+    --jobs(-j): int              # Number of files to write concurrently
     --help(-h)                   # Show help
 ]
 
@@ -109,6 +132,72 @@ export extern "tmpltr list" [
     --help(-h)                   # Show help
 ]
 
+export extern "tmpltr history" [
+    --name(-n): string           # Template name (required)
+    --help(-h)                   # Show help
+]
+
+export extern "tmpltr diff" [
+    --name(-n): string           # Template to compare (required)
+    --against: string            # Template to compare against
+    --dir(-d): string            # Directory to compare against the template
+    --ignore-files: list<string> # Files/patterns to ignore in --dir
+    --gitignore                  # Also honor .gitignore files in --dir
+    --include: list<string>      # Only compare files matching these patterns in --dir
+    --no-default-ignores         # Do not apply the built-in default ignore patterns in --dir
+    --preset: list<string>       # Ecosystem presets to apply in --dir
+    --auto-preset                # Add the presets of the ecosystems detected in --dir
+    --max-file-size: string      # Skip files larger than this size in --dir
+    --exclude-binary             # Skip binary files in --dir
+    --contents-ignore: list<string> # Files/patterns in --dir compared as structure only
+    --patch(-p)                  # Show unified diffs of modified text files
+    --help(-h)                   # Show help
+]
+
+export extern "tmpltr update" [
+    path: string                  # Project directory path
+    --to: string                 # Template version to update to
+    --dry-run                    # Show what would change without modifying files
+    --help(-h)                   # Show help
+]
+
+export extern "tmpltr export" [
+    --name(-n): string           # Template name to export (required)
+    --output(-o): string         # Archive file to write (required)
+    --format: string             # Archive format: tar, tar.gz or zip
+    --help(-h)                   # Show help
+]
+
+export extern "tmpltr import" [
+    path: string                  # Archive to import
+    --as: string                 # Name for the imported template
+    --help(-h)                   # Show help
+]
+
+export extern "tmpltr check-ignore" [
+    path: string                  # Directory to check
+    ...paths: string             # Paths relative to the directory
+    --ignore-files: list<string> # Files/patterns to ignore, as for make
+    --include: list<string>      # Include patterns, as for make
+    --gitignore                  # Also honor .gitignore files and .git/info/exclude
+    --no-default-ignores         # Do not apply the built-in default ignore patterns
+    --preset: list<string>       # Ecosystem presets, as for make
+    --auto-preset                # Add the presets of the detected ecosystems
+    --max-file-size: string      # Skip files larger than this size, as for make
+    --exclude-binary             # Skip binary files, as for make
+    --contents-ignore: list<string> # Files/patterns to save as structure only, as for make
+    --help(-h)                   # Show help
+]
+
+export extern "tmpltr init-ignore" [
+    path: string                  # Directory to inspect
+    --output(-o): string         # File to write the proposal to, "-" for stdout
+    --force                      # Replace an existing ignore file
+    --large-file-size: string    # Size from which binary files are suggested
+    --many-files: int            # Number of files from which a directory is suggested
+    --help(-h)                   # Show help
+]
+
 export extern "tmpltr delete" [
     --name(-n): string           # Template name to delete (required)
     --force(-f)                  # Skip confirmation prompt
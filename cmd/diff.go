@@ -0,0 +1,345 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"tmpltr/internal/hash"
+	"tmpltr/internal/manifest"
+	"tmpltr/internal/storage"
+	"tmpltr/internal/textdiff"
+	"tmpltr/internal/workpool"
+)
+
+// maxPatchSize is the largest file, in bytes, for which a unified diff is shown
+const maxPatchSize = 1 << 20
+
+var (
+	diffTemplateName string
+	diffAgainst      string
+	diffPatch        bool
+	diffDirectory    string
+	diffIgnoreFiles  []string
+	diffGitIgnore    bool
+	diffIncludeFiles []string
+	diffNoDefaults   bool
+	diffPresets      []string
+	diffAutoPreset   bool
+	diffMaxFileSize  string
+	diffNoBinary     bool
+	diffContents     []string
+)
+
+// diffWording names the kinds of changes in the output of the diff command
+type diffWording struct {
+	added   string // Files only present in the compared side
+	removed string // Files only present in the side compared against
+}
+
+var (
+	// templateWording describes changes between two templates
+	templateWording = diffWording{added: "added", removed: "removed"}
+
+	// directoryWording describes how a directory drifted from a template
+	directoryWording = diffWording{added: "extra", removed: "missing"}
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show differences between templates, template versions or directories",
+	Long: `Compare a template against another template or another version of the same
+template. Files are compared by path and content hash and reported as added,
+removed or modified relative to the template given with --against.
+
+With --dir, a directory is compared against the template instead, using the
+same ignore rules as make. Template files are reported as missing or modified
+in the directory, and files only present in the directory as extra. Files
+saved as structure only or holding redacted secrets are compared by presence.
+
+Templates accept "name@version" to select a version, where version is a tag,
+a version number or a date. With --patch, unified diffs of the contents of
+modified text files are shown as well.
+
+Examples:
+  tmpltr diff --name="go-service" --against="go-service@3"
+  tmpltr diff --name="go-service@v2" --against="go-service@v1" --patch
+  tmpltr diff --name="go-service" --against="rust-service"
+  tmpltr diff --name="go-service" --dir="./project" --patch
+  tmpltr diff --name="go-service" --dir="./project" --preset=go`,
+	RunE: runDiff,
+}
+
+func init() {
+	diffCmd.Flags().StringVarP(&diffTemplateName, "name", "n", "", "Template to compare (required)")
+	diffCmd.Flags().StringVar(&diffAgainst, "against", "", "Template to compare against (required)")
+	diffCmd.Flags().BoolVarP(&diffPatch, "patch", "p", false, "Show unified diffs of modified text files")
+	diffCmd.Flags().StringVarP(&diffDirectory, "dir", "d", "", "Directory to compare against the template")
+	diffCmd.Flags().StringSliceVar(&diffIgnoreFiles, "ignore-files", []string{}, "Comma-separated list of files/patterns to ignore in --dir")
+	diffCmd.Flags().StringSliceVar(&diffIncludeFiles, "include", []string{}, "Comma-separated list of patterns; only matching files are compared in --dir")
+	diffCmd.Flags().BoolVar(&diffGitIgnore, "gitignore", false, "Also honor .gitignore files in --dir (default from config)")
+	diffCmd.Flags().BoolVar(&diffNoDefaults, "no-default-ignores", false, "Do not apply the built-in default ignore patterns in --dir, as for make")
+	diffCmd.Flags().StringSliceVar(&diffPresets, "preset", []string{}, "Comma-separated list of ecosystem presets to apply in --dir, as for make")
+	diffCmd.Flags().BoolVar(&diffAutoPreset, "auto-preset", false, "Add the presets of the ecosystems detected in --dir, as for make")
+	diffCmd.Flags().StringVar(&diffMaxFileSize, "max-file-size", "", "Skip files larger than this size in --dir, as for make")
+	diffCmd.Flags().BoolVar(&diffNoBinary, "exclude-binary", false, "Skip binary files in --dir, as for make")
+	diffCmd.Flags().StringSliceVar(&diffContents, "contents-ignore", []string{}, "Comma-separated list of patterns of files in --dir compared as structure only, as for make")
+	diffCmd.MarkFlagRequired("name")
+	diffCmd.MarkFlagsOneRequired("against", "dir")
+	diffCmd.MarkFlagsMutuallyExclusive("against", "dir")
+	diffCmd.RegisterFlagCompletionFunc("preset", completePresets)
+	diffCmd.RegisterFlagCompletionFunc("dir", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	})
+
+	// Add completion for template names
+	diffCmd.RegisterFlagCompletionFunc("name", templateNameCompletion)
+	diffCmd.RegisterFlagCompletionFunc("against", templateNameCompletion)
+}
+
+// diffSide is one side of a comparison: a manifest and a way to read its contents
+type diffSide struct {
+	label    string
+	manifest *manifest.Manifest
+	open     func(entry *manifest.FileEntry) (io.ReadCloser, error)
+}
+
+// runDiff executes the diff command logic
+func runDiff(cmd *cobra.Command, args []string) error {
+	// Initialize storage
+	storage, err := storage.NewStorage("")
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	if diffDirectory != "" {
+		templateSide, err := templateDiffSide(storage, diffTemplateName)
+		if err != nil {
+			return err
+		}
+
+		gitIgnore, err := resolveGitIgnore(cmd, diffGitIgnore, storage)
+		if err != nil {
+			return err
+		}
+
+		dirSide, err := directoryDiffSide(diffDirectory, templateSide.manifest, gitIgnore)
+		if err != nil {
+			return err
+		}
+
+		return displayDiff(templateSide, dirSide, directoryWording)
+	}
+
+	newSide, err := templateDiffSide(storage, diffTemplateName)
+	if err != nil {
+		return err
+	}
+
+	oldSide, err := templateDiffSide(storage, diffAgainst)
+	if err != nil {
+		return err
+	}
+
+	return displayDiff(oldSide, newSide, templateWording)
+}
+
+// templateDiffSide loads the template version selected by ref for comparison
+func templateDiffSide(store *storage.Storage, ref string) (diffSide, error) {
+	name, m, err := store.LoadTemplateRef(ref)
+	if err != nil {
+		return diffSide{}, fmt.Errorf("failed to load template manifest: %w", err)
+	}
+
+	return diffSide{
+		label:    fmt.Sprintf("%s@%d", name, m.Version),
+		manifest: m,
+		open: func(entry *manifest.FileEntry) (io.ReadCloser, error) {
+			return store.OpenFile(name, entry.Hash, entry.Compressed)
+		},
+	}, nil
+}
+
+// directoryDiffSide scans a directory with the same ignore rules as make and hashes
+// its files for comparison against a template manifest. Files that are structure
+// only in the template, or hold redacted secrets in it, are compared by presence
+// alone, since their restored contents differ from what the template stores.
+func directoryDiffSide(dir string, template *manifest.Manifest, gitIgnore bool) (diffSide, error) {
+	if err := validateTargetDirectory(dir); err != nil {
+		return diffSide{}, err
+	}
+
+	opts := ignoreOptions{
+		patterns:   diffIgnoreFiles,
+		includes:   diffIncludeFiles,
+		gitIgnore:  gitIgnore,
+		noDefaults: diffNoDefaults,
+		presets:    diffPresets,
+		autoPreset: diffAutoPreset,
+		maxSize:    diffMaxFileSize,
+		noBinary:   diffNoBinary,
+		contents:   diffContents,
+	}
+	ignoreRules, err := setupIgnoreRules(dir, opts)
+	if err != nil {
+		return diffSide{}, err
+	}
+
+	files, _, err := collectFiles(dir, dir, ignoreRules)
+	if err != nil {
+		return diffSide{}, fmt.Errorf("failed to scan directory: %w", err)
+	}
+
+	entries := make([]manifest.FileEntry, len(files))
+	err = workpool.Run(len(files), runtime.GOMAXPROCS(0), func(i int) error {
+		entry := manifest.FileEntry{OriginalPath: files[i].relPath, IncludeContents: true}
+
+		templateEntry := template.GetFileByPath(entry.OriginalPath)
+		if templateEntry != nil && (!templateEntry.IncludeContents || templateEntry.Templated) {
+			entry.Hash = templateEntry.Hash
+			entry.IncludeContents = templateEntry.IncludeContents
+			entry.Templated = templateEntry.Templated
+		} else if files[i].structureOnly {
+			// make would capture the file as structure only, which differs from
+			// the captured contents in the template
+			entry.Hash = hash.GenerateFileNameHash(entry.OriginalPath)
+			entry.IncludeContents = false
+		} else {
+			fileHash, err := hash.HashFile(files[i].path)
+			if err != nil {
+				return err
+			}
+			entry.Hash = fileHash
+		}
+
+		info, err := os.Stat(files[i].path)
+		if err != nil {
+			return fmt.Errorf("failed to get file info for %s: %w", entry.OriginalPath, err)
+		}
+		entry.OriginalSize = info.Size()
+
+		entries[i] = entry
+		return nil
+	})
+	if err != nil {
+		return diffSide{}, err
+	}
+
+	m := manifest.NewManifest(dir)
+	m.Files = entries
+
+	return diffSide{
+		label:    filepath.Clean(dir),
+		manifest: m,
+		open: func(entry *manifest.FileEntry) (io.ReadCloser, error) {
+			return os.Open(filepath.Join(dir, entry.OriginalPath))
+		},
+	}, nil
+}
+
+// displayDiff prints the changes between two sides and, with --patch, their unified diffs
+func displayDiff(oldSide, newSide diffSide, wording diffWording) error {
+	changes := manifest.Compare(oldSide.manifest, newSide.manifest)
+
+	fmt.Printf("Comparing '%s' against '%s':\n", newSide.label, oldSide.label)
+	if len(changes) == 0 {
+		fmt.Println("No differences found")
+		return nil
+	}
+
+	for _, change := range changes {
+		switch change.Kind {
+		case manifest.ChangeAdded:
+			fmt.Printf("  + %s (%s)\n", change.Path, wording.added)
+		case manifest.ChangeModified:
+			fmt.Printf("  ~ %s (modified)\n", change.Path)
+		case manifest.ChangeRemoved:
+			fmt.Printf("  - %s (%s)\n", change.Path, wording.removed)
+		}
+	}
+
+	added, modified, removed := manifest.CountChanges(changes)
+	fmt.Printf("\n%d %s, %d modified, %d %s\n", added, wording.added, modified, removed, wording.removed)
+
+	if !diffPatch {
+		return nil
+	}
+
+	for _, change := range changes {
+		patch, err := changePatch(change, oldSide, newSide)
+		if err != nil {
+			return fmt.Errorf("failed to diff %s: %w", change.Path, err)
+		}
+		if patch != "" {
+			fmt.Println()
+			fmt.Print(patch)
+		}
+	}
+
+	return nil
+}
+
+// changePatch returns the unified diff of a single changed file
+func changePatch(change manifest.Change, oldSide, newSide diffSide) (string, error) {
+	oldLabel, newLabel := "/dev/null", "/dev/null"
+	if change.Old != nil {
+		oldLabel = oldSide.label + "/" + change.Path
+	}
+	if change.New != nil {
+		newLabel = newSide.label + "/" + change.Path
+	}
+
+	oldContent, oldNote, err := readDiffContent(change.Old, oldSide)
+	if err != nil {
+		return "", err
+	}
+	newContent, newNote, err := readDiffContent(change.New, newSide)
+	if err != nil {
+		return "", err
+	}
+
+	note := oldNote
+	if note == "" {
+		note = newNote
+	}
+	if note != "" {
+		return fmt.Sprintf("--- %s\n+++ %s\n%s\n", oldLabel, newLabel, note), nil
+	}
+
+	return textdiff.Unified(oldLabel, newLabel, string(oldContent), string(newContent), textdiff.DefaultContext), nil
+}
+
+// readDiffContent reads the contents of a file entry for diffing. If the contents
+// cannot be shown as text, a note explaining why is returned instead.
+func readDiffContent(entry *manifest.FileEntry, side diffSide) ([]byte, string, error) {
+	if entry == nil {
+		return nil, "", nil
+	}
+	if !entry.IncludeContents {
+		return nil, fmt.Sprintf("Contents of %s/%s were not saved (structure only)", side.label, entry.OriginalPath), nil
+	}
+	if entry.OriginalSize > maxPatchSize {
+		return nil, fmt.Sprintf("File %s/%s is too large to diff", side.label, entry.OriginalPath), nil
+	}
+
+	reader, err := side.open(entry)
+	if err != nil {
+		return nil, "", err
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(io.LimitReader(reader, maxPatchSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", entry.OriginalPath, err)
+	}
+	if textdiff.IsBinary(content) {
+		return nil, fmt.Sprintf("Binary file %s/%s differs", side.label, entry.OriginalPath), nil
+	}
+
+	return content, "", nil
+}
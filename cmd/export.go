@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"tmpltr/internal/bundle"
+	"tmpltr/internal/manifest"
+	"tmpltr/internal/storage"
+)
+
+var (
+	exportTemplateName string
+	exportOutput       string
+	exportFormat       string
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a template to a portable archive",
+	Long: `Export a template to a single archive file containing its manifest and exactly
+the file contents it references. The archive format is chosen from the output
+file extension (.tar.gz, .tgz, .tar or .zip) unless --format is given.
+
+The archive can be imported on another machine with 'tmpltr import'.
+
+Examples:
+  tmpltr export --name="my-template" --output="my-template.tar.gz"
+  tmpltr export --name="my-template@v2" --output="my-template-v2.zip"`,
+	RunE: runExport,
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportTemplateName, "name", "n", "", "Name of the template to export (required)")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Archive file to write (required)")
+	exportCmd.Flags().StringVar(&exportFormat, "format", "", "Archive format: tar, tar.gz or zip (default from output extension)")
+	exportCmd.MarkFlagRequired("name")
+	exportCmd.MarkFlagRequired("output")
+
+	// Add completion for template names
+	exportCmd.RegisterFlagCompletionFunc("name", templateNameCompletion)
+	exportCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"tar", "tar.gz", "zip"}, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// runExport executes the export command logic
+func runExport(cmd *cobra.Command, args []string) error {
+	format, err := exportArchiveFormat()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(exportOutput); err == nil {
+		return fmt.Errorf("output file already exists: %s", exportOutput)
+	}
+
+	// Initialize storage
+	storage, err := storage.NewStorage("")
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	name, m, err := storage.LoadTemplateRef(exportTemplateName)
+	if err != nil {
+		return fmt.Errorf("failed to load template manifest: %w", err)
+	}
+
+	// Write to a temporary file first so that a failed export leaves no partial archive
+	tmpFile, err := os.CreateTemp(filepath.Dir(exportOutput), ".tmp-export-*")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	blobCount, err := writeBundle(tmpFile, format, name, m, storage)
+	if closeErr := tmpFile.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write output file: %w", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpFile.Name(), exportOutput); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	fmt.Printf("Exported template '%s' (version %d) with %d files and %d stored contents to: %s\n",
+		name, m.Version, m.GetFileCount(), blobCount, exportOutput)
+
+	return nil
+}
+
+// exportArchiveFormat returns the archive format selected by --format or the output extension
+func exportArchiveFormat() (bundle.Format, error) {
+	if exportFormat != "" {
+		return bundle.ParseFormat(exportFormat)
+	}
+	return bundle.DetectFormat(exportOutput)
+}
+
+// writeBundle writes the manifest and the blobs it references to a bundle and
+// returns the number of blobs written
+func writeBundle(file *os.File, format bundle.Format, templateName string, m *manifest.Manifest, storage *storage.Storage) (int, error) {
+	writer, err := bundle.NewWriter(file, format, m)
+	if err != nil {
+		return 0, err
+	}
+
+	written := make(map[string]bool)
+	for _, entry := range m.Files {
+		if !entry.StoresContents() || written[entry.Hash] {
+			continue
+		}
+		written[entry.Hash] = true
+
+		if err := addBlobToBundle(writer, storage.GetFileContentPath(templateName, entry.Hash), entry.Hash); err != nil {
+			return 0, fmt.Errorf("failed to export %s: %w", entry.OriginalPath, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+
+	return len(written), nil
+}
+
+// addBlobToBundle adds a stored blob to a bundle as it is stored, compressed or not
+func addBlobToBundle(writer *bundle.Writer, blobPath, hash string) error {
+	blob, err := os.Open(blobPath)
+	if err != nil {
+		return fmt.Errorf("failed to open stored contents: %w", err)
+	}
+	defer blob.Close()
+
+	info, err := blob.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to open stored contents: %w", err)
+	}
+
+	return writer.AddFile(bundle.BlobPath(hash), info.Size(), blob)
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"tmpltr/internal/manifest"
+	"tmpltr/internal/storage"
+)
+
+var historyTemplateName string
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the versions of a template",
+	Long: `List all versions of a template, newest first, with their tags, creation
+dates and the change in file count and size relative to the previous version.
+
+Any version can be restored with "name@version".
+
+Example:
+  tmpltr history --name="my-template"`,
+	RunE: runHistory,
+}
+
+func init() {
+	historyCmd.Flags().StringVarP(&historyTemplateName, "name", "n", "", "Name of the template (required)")
+	historyCmd.MarkFlagRequired("name")
+
+	// Add completion for template names
+	historyCmd.RegisterFlagCompletionFunc("name", templateNameCompletion)
+}
+
+// runHistory executes the history command logic
+func runHistory(cmd *cobra.Command, args []string) error {
+	if historyTemplateName == "" {
+		return fmt.Errorf("template name cannot be empty")
+	}
+
+	// Initialize storage
+	storage, err := storage.NewStorage("")
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	// Check if template exists
+	if !storage.TemplateExists(historyTemplateName) {
+		return fmt.Errorf("template '%s' does not exist", historyTemplateName)
+	}
+
+	versions, err := storage.ListVersions(historyTemplateName)
+	if err != nil {
+		return fmt.Errorf("failed to list template versions: %w", err)
+	}
+
+	displayHistory(historyTemplateName, versions)
+	return nil
+}
+
+// displayHistory prints the versions of a template, newest first
+func displayHistory(templateName string, versions []*manifest.Manifest) {
+	fmt.Printf("Template '%s' has %d version(s):\n\n", templateName, len(versions))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tTAG\tCREATED\tFILES\tSIZE")
+
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		_, size, _ := v.GetCompressionStats()
+
+		files := fmt.Sprintf("%d", v.GetFileCount())
+		sizeText := fmt.Sprintf("%.1f KB", float64(size)/1024)
+		if i > 0 {
+			_, previousSize, _ := versions[i-1].GetCompressionStats()
+			files += fmt.Sprintf(" (%+d)", v.GetFileCount()-versions[i-1].GetFileCount())
+			sizeText += fmt.Sprintf(" (%+.1f KB)", float64(size-previousSize)/1024)
+		}
+
+		tag := v.Tag
+		if tag == "" {
+			tag = "-"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", v.Version, tag,
+			v.VersionTime().Format("2006-01-02 15:04:05 MST"), files, sizeText)
+	}
+
+	w.Flush()
+
+	fmt.Printf("\nUse 'tmpltr restore --name=\"%s@<version>\" --output=\"<directory>\"' to restore a version.\n", templateName)
+}
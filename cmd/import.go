@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"tmpltr/internal/bundle"
+	"tmpltr/internal/compression"
+	"tmpltr/internal/manifest"
+	"tmpltr/internal/storage"
+)
+
+var importAsName string
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <archive>",
+	Short: "Import a template from an exported archive",
+	Long: `Import a template from an archive created with 'tmpltr export'. The contents
+of every file are verified against their hashes while they are imported, and
+the import fails without leaving a template behind if any of them do not match.
+
+The template keeps its exported name unless a new one is given with --as.
+
+Examples:
+  tmpltr import my-template.tar.gz
+  tmpltr import my-template.zip --as="their-template"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImport,
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importAsName, "as", "", "Name for the imported template (default the exported name)")
+}
+
+// runImport executes the import command logic
+func runImport(cmd *cobra.Command, args []string) error {
+	reader, err := bundle.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	name := reader.Manifest.Name
+	if importAsName != "" {
+		name = importAsName
+	}
+
+	// Validate template name
+	if err := validateTemplateName(name); err != nil {
+		return err
+	}
+
+	// Initialize storage
+	storage, err := storage.NewStorage("")
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	// Check if template already exists
+	if storage.TemplateExists(name) {
+		return fmt.Errorf("template '%s' already exists (use --as to import it under another name)", name)
+	}
+
+	if err := storage.EnsureTemplateDir(name); err != nil {
+		return fmt.Errorf("failed to create template directory: %w", err)
+	}
+
+	m := reader.Manifest
+	if err := importBlobs(reader, name, m, storage); err != nil {
+		storage.DeleteTemplate(name)
+		return err
+	}
+
+	m.Name = name
+	if err := storage.SaveVersion(name, m); err != nil {
+		storage.DeleteTemplate(name)
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+
+	fmt.Printf("Successfully imported template '%s' with %d files from: %s\n", name, m.GetFileCount(), args[0])
+	return nil
+}
+
+// importBlobs stores the blobs of a bundle in the template, verifying that each
+// one matches its hash and that exactly the blobs referenced by the manifest are present
+func importBlobs(reader *bundle.Reader, templateName string, m *manifest.Manifest, storage *storage.Storage) error {
+	entriesByHash := make(map[string][]*manifest.FileEntry)
+	for i := range m.Files {
+		entry := &m.Files[i]
+		if entry.StoresContents() {
+			entriesByHash[entry.Hash] = append(entriesByHash[entry.Hash], entry)
+		} else if err := storage.SaveFile(templateName, entry.Hash, []byte("")); err != nil {
+			return fmt.Errorf("failed to save empty file placeholder: %w", err)
+		}
+	}
+
+	imported := make(map[string]bool)
+	for {
+		blobHash, content, err := reader.NextBlob()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		entries := entriesByHash[blobHash]
+		if len(entries) == 0 {
+			return fmt.Errorf("invalid bundle: contents %s are not referenced by the manifest", blobHash)
+		}
+		if imported[blobHash] {
+			return fmt.Errorf("invalid bundle: contents %s appear more than once", blobHash)
+		}
+
+		blob, err := importBlob(content, templateName, entries[0].Compressed, storage)
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", entries[0].OriginalPath, err)
+		}
+		if blob.Hash != blobHash {
+			return fmt.Errorf("hash mismatch for %s: bundle is corrupt", entries[0].OriginalPath)
+		}
+		imported[blobHash] = true
+
+		for _, entry := range entries {
+			entry.Compressed = blob.Compressed
+			entry.StoredSize = blob.StoredSize
+		}
+	}
+
+	for blobHash, entries := range entriesByHash {
+		if !imported[blobHash] {
+			return fmt.Errorf("invalid bundle: contents of %s are missing", entries[0].OriginalPath)
+		}
+	}
+
+	return nil
+}
+
+// importBlob stores a blob read from a bundle, re-hashing its original contents
+func importBlob(content io.Reader, templateName string, compressed bool, store *storage.Storage) (storage.BlobInfo, error) {
+	if compressed {
+		decompressed, err := compression.NewDecompressReader(content)
+		if err != nil {
+			return storage.BlobInfo{}, err
+		}
+		defer decompressed.Close()
+		content = decompressed
+	}
+
+	return store.SaveStream(templateName, content, compressed)
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"tmpltr/internal/ignore"
+)
+
+var (
+	initIgnoreOutput    string
+	initIgnoreForce     bool
+	initIgnoreLargeSize string
+	initIgnoreManyFiles int
+)
+
+// initIgnoreCmd represents the init-ignore command
+var initIgnoreCmd = &cobra.Command{
+	Use:   "init-ignore <directory>",
+	Short: "Propose a .tmpltrignore file for a directory",
+	Long: `Inspect a directory and write a commented .tmpltrignore proposal with the
+rules the built-in defaults miss: the build output and caches of ecosystems
+detected from marker files such as go.mod or package.json, files that commonly
+hold secrets, directories holding many files and large binary files.
+
+Each rule is preceded by a comment with the number of files and the size it
+excludes beyond the rules above it. Review the proposal before making a
+template. The file is written to the directory unless --output is given, "-"
+writing it to stdout, and an existing file is only replaced with --force.
+
+Examples:
+  tmpltr init-ignore ./my-project
+  tmpltr init-ignore ./my-project --output=- --large-file-size=1MB
+  tmpltr init-ignore ./my-project --many-files=500 --force`,
+	Args: cobra.ExactArgs(1),
+	RunE: runInitIgnore,
+}
+
+func init() {
+	initIgnoreCmd.Flags().StringVarP(&initIgnoreOutput, "output", "o", "", "File to write the proposal to, \"-\" for stdout (default <directory>/.tmpltrignore)")
+	initIgnoreCmd.Flags().BoolVar(&initIgnoreForce, "force", false, "Replace an existing ignore file")
+	initIgnoreCmd.Flags().StringVar(&initIgnoreLargeSize, "large-file-size", "10MB", "Size from which binary files are suggested")
+	initIgnoreCmd.Flags().IntVar(&initIgnoreManyFiles, "many-files", 1000, "Number of files from which a directory is suggested")
+
+	// Add completion for directory arguments
+	initIgnoreCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	}
+}
+
+// runInitIgnore executes the init-ignore command logic
+func runInitIgnore(cmd *cobra.Command, args []string) error {
+	dir := args[0]
+	if err := validateTargetDirectory(dir); err != nil {
+		return err
+	}
+
+	largeSize, err := ignore.ParseSize(initIgnoreLargeSize)
+	if err != nil {
+		return err
+	}
+	if initIgnoreManyFiles < 1 {
+		return fmt.Errorf("--many-files must be at least 1")
+	}
+
+	output := initIgnoreOutput
+	if output == "" {
+		output = filepath.Join(dir, ignore.IgnoreFileName)
+	}
+	if output != "-" && !initIgnoreForce {
+		if _, err := os.Stat(output); err == nil {
+			return fmt.Errorf("ignore file already exists: %s (use --force to replace it)", output)
+		}
+	}
+
+	suggestions, err := ignore.Suggest(dir, ignore.SuggestOptions{
+		LargeFileSize: largeSize,
+		ManyFiles:     initIgnoreManyFiles,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to inspect directory: %w", err)
+	}
+
+	if len(suggestions) == 0 {
+		fmt.Fprintln(os.Stderr, "No ignore rules to suggest beyond the built-in defaults")
+		return nil
+	}
+
+	if output == "-" {
+		return writeIgnoreProposal(os.Stdout, suggestions)
+	}
+
+	var content strings.Builder
+	if err := writeIgnoreProposal(&content, suggestions); err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, []byte(content.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write ignore file: %w", err)
+	}
+
+	files, size := suggestionTotals(suggestions)
+	fmt.Printf("Wrote %d rule(s) to %s, excluding %d files (%s)\n", len(suggestions), output, files, ignore.FormatSize(size))
+	return nil
+}
+
+// writeIgnoreProposal writes the suggestions as a commented .tmpltrignore file,
+// grouping consecutive rules with the same reason
+func writeIgnoreProposal(w io.Writer, suggestions []ignore.Suggestion) error {
+	var b strings.Builder
+	b.WriteString("# .tmpltrignore proposed by tmpltr init-ignore\n")
+	b.WriteString("# Review the rules before making a template. Each comment gives the files\n")
+	b.WriteString("# and size the rule excludes beyond the rules above it. The built-in\n")
+	b.WriteString("# defaults, such as .git/, node_modules/ and *.log, apply as well.\n")
+
+	reason := ""
+	for _, s := range suggestions {
+		if s.Reason != reason {
+			reason = s.Reason
+			fmt.Fprintf(&b, "\n# %s\n", strings.ToUpper(reason[:1])+reason[1:])
+		}
+		fmt.Fprintf(&b, "# %d file(s), %s\n%s\n", s.Files, ignore.FormatSize(s.Size), s.Pattern)
+	}
+
+	files, size := suggestionTotals(suggestions)
+	fmt.Fprintf(&b, "\n# Total excluded: %d file(s), %s\n", files, ignore.FormatSize(size))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// suggestionTotals returns the number of files and the size all suggestions exclude
+func suggestionTotals(suggestions []ignore.Suggestion) (int, int64) {
+	files, size := 0, int64(0)
+	for _, s := range suggestions {
+		files += s.Files
+		size += s.Size
+	}
+	return files, size
+}
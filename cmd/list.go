@@ -26,6 +26,10 @@ Example:
 type TemplateInfo struct {
 	Name            string
 	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Version         int
+	Tag             string
+	VersionCount    int
 	FileCount       int
 	ContentFiles    int
 	StructureFiles  int
@@ -88,6 +92,11 @@ func getTemplateInfo(templateName string, storage *storage.Storage) (TemplateInf
 		return TemplateInfo{}, err
 	}
 
+	versions, err := storage.ListVersions(templateName)
+	if err != nil {
+		return TemplateInfo{}, err
+	}
+
 	contentFiles := len(manifest.GetFilesWithContents())
 	totalFiles := manifest.GetFileCount()
 	structureFiles := totalFiles - contentFiles
@@ -96,6 +105,10 @@ func getTemplateInfo(templateName string, storage *storage.Storage) (TemplateInf
 	return TemplateInfo{
 		Name:            manifest.Name,
 		CreatedAt:       manifest.CreatedAt,
+		UpdatedAt:       manifest.UpdatedAt,
+		Version:         manifest.Version,
+		Tag:             manifest.Tag,
+		VersionCount:    len(versions),
 		FileCount:       totalFiles,
 		ContentFiles:    contentFiles,
 		StructureFiles:  structureFiles,
@@ -116,6 +129,16 @@ func displayTemplates(templates []TemplateInfo) {
 
 		fmt.Printf("📁 %s\n", template.Name)
 		fmt.Printf("   Created: %s\n", template.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+		if !template.UpdatedAt.IsZero() {
+			fmt.Printf("   Updated: %s\n", template.UpdatedAt.Format("2006-01-02 15:04:05 MST"))
+		}
+		if template.VersionCount > 1 {
+			fmt.Printf("   Version: %d of %d", template.Version, template.VersionCount)
+			if template.Tag != "" {
+				fmt.Printf(" (%s)", template.Tag)
+			}
+			fmt.Println()
+		}
 		fmt.Printf("   Files:   %d total", template.FileCount)
 
 		if template.ContentFiles > 0 && template.StructureFiles > 0 {
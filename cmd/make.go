@@ -1,25 +1,74 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"tmpltr/internal/archive"
+	"tmpltr/internal/cache"
+	"tmpltr/internal/compression"
+	"tmpltr/internal/config"
+	"tmpltr/internal/git"
 	"tmpltr/internal/hash"
 	"tmpltr/internal/ignore"
 	"tmpltr/internal/manifest"
+	"tmpltr/internal/placeholder"
+	"tmpltr/internal/secrets"
 	"tmpltr/internal/storage"
+	"tmpltr/internal/workpool"
 )
 
+// stdinTarget is the make target that reads a tar stream from stdin
+const stdinTarget = "-"
+
 var (
 	templateName    string
 	ignoreContents  bool
 	ignoreFiles     []string
 	noCompression   bool
+	makeJobs        int
+	noCache         bool
+	updateTemplate  bool
+	versionTag      string
+	makeFromArchive string
+	makeGitRepo     string
+	makeGitRef      string
+	makeGitIgnore   bool
+	explainIgnores  bool
+	includeFiles    []string
+	noDefaultIgnore bool
+	makePresets     []string
+	makeAutoPreset  bool
+	maxFileSize     string
+	excludeBinary   bool
+	contentsIgnore  []string
+	allowSecrets    bool
+	redactSecrets   bool
+	placeholderKind string
+	headerLines     int
+
+	// hashCache is the hash cache consulted by processFile, nil if disabled
+	hashCache *cache.Cache
+
+	// secretAllowlist lists the files processFile may capture with secrets, nil
+	// if files are not scanned for secrets
+	secretAllowlist *secrets.Allowlist
+
+	// secretRedactor replaces the secrets found by variable placeholders, nil
+	// if templates with secrets are refused
+	secretRedactor *secrets.Redactor
 )
 
 // makeCmd represents the make command
@@ -30,21 +79,67 @@ var makeCmd = &cobra.Command{
 hashing their contents, and saving the structure with optional file contents.
 Supports compression and selective file ignoring.
 
+Files are ignored with .gitignore-style patterns from --ignore-files and from
+.tmpltrignore files in any directory (see example.tmpltrignore); run
+'tmpltr check-ignore' to see which pattern decided about a path. Captured
+contents are scanned for likely secrets, and no template is created if any
+are found unless they are allowed or redacted.
+
 Examples:
   tmpltr make ./my-project --name="my-template"
   tmpltr make ./my-project --name="structure-only" --ignore-contents
   tmpltr make ./my-project --name="selective" --ignore-files="*.log,node_modules/,temp.txt"
-  tmpltr make ./my-project --name="uncompressed" --no-compression`,
-	Args: cobra.ExactArgs(1),
+  tmpltr make ./my-project --name="config" --include="*.yaml,Makefile,.github/**"
+  tmpltr make ./my-project --name="service" --preset=go,jetbrains
+  tmpltr make ./my-project --name="lean" --max-file-size=10MB --exclude-binary
+  tmpltr make ./my-project --name="no-data" --contents-ignore="data/**,*.sqlite"
+  tmpltr make ./my-project --name="api-shape" --ignore-contents --placeholder=skeleton
+  tmpltr make ./my-project --name="with-config" --redact-secrets
+  tmpltr make ./my-project --name="my-template" --update --tag="v2"
+  tmpltr make --from-archive=build.tar.gz --name="build-output"
+  tmpltr make --git=../go-service --ref="v1.4.0" --name="go-service"`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runMake,
 }
 
 func init() {
 	makeCmd.Flags().StringVarP(&templateName, "name", "n", "", "Name for the template (required)")
 	makeCmd.Flags().BoolVar(&ignoreContents, "ignore-contents", false, "Only save file structure, ignore contents")
-	makeCmd.Flags().StringSliceVar(&ignoreFiles, "ignore-files", []string{}, "Comma-separated list of files/patterns to ignore")
+	makeCmd.Flags().StringSliceVar(&ignoreFiles, "ignore-files", []string{}, "Comma-separated list of files/patterns to ignore, taking precedence over .tmpltrignore files")
+	makeCmd.Flags().StringSliceVar(&contentsIgnore, "contents-ignore", []string{}, "Comma-separated list of patterns of files to save as structure only, as \"contents-ignore:\" lines do in .tmpltrignore")
+	makeCmd.Flags().StringVar(&placeholderKind, "placeholder", manifest.PlaceholderEmpty, "Placeholder restored for structure-only files: empty, header (first lines), skeleton (Go declarations without bodies) or sized (sparse file)")
+	makeCmd.Flags().IntVar(&headerLines, "placeholder-lines", 10, "Number of lines kept by --placeholder=header")
+	makeCmd.Flags().BoolVar(&allowSecrets, "allow-secrets", false, "Capture files even if they appear to contain secrets; to allow only some files, list them in a .tmpltrsecrets file")
+	makeCmd.Flags().BoolVar(&redactSecrets, "redact-secrets", false, "Replace secrets by variables such as {{ .Secret_DB_PASSWORD }}, whose values are supplied on restore, instead of refusing them")
 	makeCmd.Flags().BoolVar(&noCompression, "no-compression", false, "Disable compression of file contents")
+	makeCmd.Flags().BoolVar(&updateTemplate, "update", false, "Replace the contents of an existing template, keeping its metadata")
+	makeCmd.Flags().StringVar(&versionTag, "tag", "", "Tag for the created template version, e.g. \"v2\"")
+	makeCmd.Flags().BoolVar(&noCache, "no-cache", false, "Hash every file instead of reusing hashes of unchanged files")
+	makeCmd.Flags().StringVar(&makeFromArchive, "from-archive", "", "Create the template from a tar, tar.gz or zip archive instead of a directory; pass \"-\" as the target to read a tar stream from stdin")
+	makeCmd.Flags().StringVar(&makeGitRepo, "git", "", "Create the template from the tracked files of a commit of a git repository (path or file:// URL)")
+	makeCmd.Flags().StringVar(&makeGitRef, "ref", "", "Commit, branch or tag to capture with --git (default HEAD)")
+	makeCmd.Flags().BoolVar(&makeGitIgnore, "gitignore", false, "Also honor .gitignore files and .git/info/exclude (default from \"gitignore\" in ~/.tmpltr/config.json)")
+	makeCmd.Flags().StringSliceVar(&includeFiles, "include", []string{}, "Comma-separated list of patterns; only matching files are captured, as with a .tmpltrinclude file")
+	makeCmd.Flags().BoolVar(&noDefaultIgnore, "no-default-ignores", false, "Do not apply the built-in default ignore patterns, such as \"*.log\" and \".git/\"")
+	makeCmd.Flags().StringSliceVar(&makePresets, "preset", []string{}, "Comma-separated list of ecosystem presets to ignore the build output of ("+strings.Join(ignore.PresetNames(), ", ")+")")
+	makeCmd.Flags().BoolVar(&makeAutoPreset, "auto-preset", false, "Add the presets of the ecosystems detected from marker files in the directory, such as go.mod or package.json")
+	makeCmd.Flags().StringVar(&maxFileSize, "max-file-size", "", "Skip files larger than this size, e.g. \"10MB\", as \"size>10MB\" lines do in .tmpltrignore")
+	makeCmd.Flags().BoolVar(&excludeBinary, "exclude-binary", false, "Skip binary files, as \"type:binary\" lines do in .tmpltrignore")
+	makeCmd.Flags().BoolVar(&explainIgnores, "explain-ignores", false, "Print the pattern and its source for every path matched by an ignore rule")
+	makeCmd.Flags().IntVarP(&makeJobs, "jobs", "j", runtime.GOMAXPROCS(0), "Number of files to hash and compress concurrently")
 	makeCmd.MarkFlagRequired("name")
+	makeCmd.RegisterFlagCompletionFunc("from-archive", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"tar", "tar.gz", "tgz", "zip"}, cobra.ShellCompDirectiveFilterFileExt
+	})
+	makeCmd.RegisterFlagCompletionFunc("git", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	})
+	makeCmd.RegisterFlagCompletionFunc("preset", completePresets)
+	makeCmd.RegisterFlagCompletionFunc("placeholder", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{manifest.PlaceholderEmpty, manifest.PlaceholderHeader, manifest.PlaceholderSkeleton, manifest.PlaceholderSized}, cobra.ShellCompDirectiveNoFileComp
+	})
+	makeCmd.MarkFlagsMutuallyExclusive("from-archive", "git")
+	makeCmd.MarkFlagsMutuallyExclusive("allow-secrets", "redact-secrets")
 	
 	// Add completion for directory arguments
 	makeCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -54,11 +149,41 @@ func init() {
 
 // runMake executes the make command logic
 func runMake(cmd *cobra.Command, args []string) error {
-	targetDir := args[0]
-	
+	// Determine the source of the files: a directory, an archive, stdin or git
+	var targetDir string
+	if len(args) > 0 {
+		targetDir = args[0]
+	}
+	if makeGitRef != "" && makeGitRepo == "" {
+		return fmt.Errorf("--ref requires --git")
+	}
+	if (makeFromArchive != "" || makeGitRepo != "") && targetDir != "" {
+		return fmt.Errorf("a target directory cannot be combined with --from-archive or --git")
+	}
+	if makeFromArchive == "" && makeGitRepo == "" && targetDir == "" {
+		return fmt.Errorf("a target directory, \"-\" for stdin, --from-archive or --git is required")
+	}
+	fromArchive := makeFromArchive != "" || makeGitRepo != "" || targetDir == stdinTarget
+
+	// Resolve the commit first so that a bad ref fails before anything is stored
+	var source *manifest.Source
+	var gitPath string
+	if makeGitRepo != "" {
+		var err error
+		if source, gitPath, err = resolveGitSource(makeGitRepo, makeGitRef); err != nil {
+			return err
+		}
+	}
+
 	// Validate target directory
-	if err := validateTargetDirectory(targetDir); err != nil {
-		return err
+	if !fromArchive {
+		if err := validateTargetDirectory(targetDir); err != nil {
+			return err
+		}
+	} else if makeFromArchive != "" {
+		if _, err := os.Stat(makeFromArchive); err != nil {
+			return fmt.Errorf("failed to access archive: %w", err)
+		}
 	}
 
 	// Validate template name
@@ -66,15 +191,54 @@ func runMake(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if err := validateVersionTag(versionTag); err != nil {
+		return err
+	}
+
+	if err := validateJobs(makeJobs); err != nil {
+		return err
+	}
+
+	if err := validatePlaceholder(placeholderKind, headerLines); err != nil {
+		return err
+	}
+
 	// Initialize storage
 	storage, err := storage.NewStorage("")
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 
-	// Check if template already exists
-	if storage.TemplateExists(templateName) {
-		return fmt.Errorf("template '%s' already exists", templateName)
+	// Check if template already exists; updates require it to exist
+	var previous *manifest.Manifest
+	if updateTemplate {
+		if !storage.TemplateExists(templateName) {
+			return fmt.Errorf("template '%s' does not exist", templateName)
+		}
+
+		previous, err = storage.LoadManifest(templateName)
+		if err != nil {
+			return fmt.Errorf("failed to load template manifest: %w", err)
+		}
+
+		versions, err := storage.ListVersions(templateName)
+		if err != nil {
+			return fmt.Errorf("failed to list template versions: %w", err)
+		}
+
+		for _, v := range versions {
+			// Fail before capturing anything if the tag is already taken
+			if versionTag != "" && v.Tag == versionTag {
+				return fmt.Errorf("tag '%s' is already used by version %d", versionTag, v.Version)
+			}
+
+			// Reuse the blobs of every version instead of writing them again, so
+			// that blobs only older versions reference are never replaced
+			storage.RegisterBlobs(templateName, v)
+		}
+		storage.RegisterBlobs(templateName, previous)
+	} else if storage.TemplateExists(templateName) {
+		return fmt.Errorf("template '%s' already exists (use --update to replace its contents)", templateName)
 	}
 
 	// Create template structure
@@ -82,27 +246,108 @@ func runMake(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create template directory: %w", err)
 	}
 
-	// Create manifest
+	// Create manifest, keeping the creation date of an updated template
 	m := manifest.NewManifest(templateName)
+	if previous != nil {
+		m.CreatedAt = previous.CreatedAt
+		m.UpdatedAt = time.Now().UTC()
+	}
 
-	// Setup ignore rules
-	ignoreRules := ignore.NewIgnoreRules(targetDir)
-	
-	// Add default ignore patterns
-	ignoreRules.AddDefaultPatterns()
-	
-	// Load .tmpltrignore file if it exists
-	if err := ignoreRules.LoadIgnoreFile(); err != nil {
-		return fmt.Errorf("failed to load ignore file: %w", err)
+	if explainIgnores {
+		fmt.Println("Ignore decisions:")
 	}
-	
-	// Add command-line ignore patterns
-	ignoreRules.AddPatterns(ignoreFiles)
 
-	// Scan and process files
-	err = scanDirectory(targetDir, targetDir, m, storage, ignoreRules)
-	if err != nil {
-		return fmt.Errorf("failed to scan directory: %w", err)
+	// Files skipped by size and type rules and the secrets found, reported with the results
+	var result scanResult
+
+	// Scan the captured files for secrets unless they are allowed
+	if !allowSecrets {
+		secretAllowlist = &secrets.Allowlist{}
+	}
+	if redactSecrets {
+		secretRedactor = secrets.NewRedactor()
+	}
+
+	if fromArchive {
+		// Read the archive from the file, stdin or git without extracting it
+		walk := func(fn archive.WalkFunc) error {
+			switch {
+			case source != nil:
+				return git.Walk(gitPath, source.Commit, fn)
+			case makeFromArchive != "":
+				return archive.WalkFile(makeFromArchive, fn)
+			default:
+				return archive.WalkStream(os.Stdin, fn)
+			}
+		}
+		m.Source = source
+
+		opts := makeIgnoreOptions()
+		if result, err = scanArchive(walk, m, storage, opts); err != nil {
+			return fmt.Errorf("failed to scan archive: %w", err)
+		}
+	} else {
+		gitIgnore, err := resolveGitIgnore(cmd, makeGitIgnore, storage)
+		if err != nil {
+			return err
+		}
+
+		// Setup ignore rules
+		opts := makeIgnoreOptions()
+		opts.gitIgnore = gitIgnore
+		ignoreRules, err := setupIgnoreRules(targetDir, opts)
+		if err != nil {
+			return err
+		}
+
+		// Load the hash cache so unchanged files need not be read again
+		if !noCache {
+			hashCache = cache.Load(storage.GetCachePath())
+		}
+
+		// Load the files allowed to hold secrets
+		if secretAllowlist != nil {
+			if secretAllowlist, err = secrets.LoadAllowlist(filepath.Join(targetDir, secrets.AllowFileName)); err != nil {
+				return err
+			}
+		}
+
+		// Scan and process files
+		result, err = scanDirectory(targetDir, targetDir, m, storage, ignoreRules)
+		if err != nil {
+			return fmt.Errorf("failed to scan directory: %w", err)
+		}
+
+		if hashCache != nil {
+			if absDir, err := filepath.Abs(targetDir); err == nil {
+				hashCache.PruneUnder(absDir)
+			}
+			if err := hashCache.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}
+
+	// Refuse to store a template with secrets, removing a newly created one
+	if len(result.secrets) > 0 {
+		if previous == nil {
+			storage.DeleteTemplate(templateName)
+		}
+		return reportSecrets(result.secrets)
+	}
+
+	// Register the variables replacing the secrets redacted from the captured
+	// files, leaving out those of files that were later excluded or replaced
+	if secretRedactor != nil {
+		var templated []string
+		for _, file := range m.Files {
+			if file.Templated {
+				templated = append(templated, filepath.ToSlash(file.OriginalPath))
+			}
+		}
+		for _, v := range secretRedactor.Variables(templated) {
+			m.AddVariable(v)
+		}
 	}
 
 	// Validate manifest
@@ -110,20 +355,35 @@ func runMake(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid manifest generated: %w", err)
 	}
 
-	// Save manifest
-	if err := storage.SaveManifest(templateName, m); err != nil {
+	// Save manifest as a new version of the template
+	m.Tag = versionTag
+	if err := storage.SaveVersion(templateName, m); err != nil {
 		return fmt.Errorf("failed to save manifest: %w", err)
 	}
 
 	// Display results
 	compressedFiles, originalSize, storedSize := m.GetCompressionStats()
 	
-	fmt.Printf("Successfully created template '%s' with %d files\n", templateName, m.GetFileCount())
+	if previous != nil {
+		displayUpdateChanges(manifest.Compare(previous, m))
+		fmt.Printf("Successfully updated template '%s' to version %d with %d files\n", templateName, m.Version, m.GetFileCount())
+	} else {
+		fmt.Printf("Successfully created template '%s' with %d files\n", templateName, m.GetFileCount())
+	}
+	displaySkippedFiles(result.skipped)
+	displayRedactedSecrets(m)
+	if m.Source != nil {
+		fmt.Printf("Captured commit %s of %s\n", m.Source.Commit, m.Source.Repository)
+	}
 	if ignoreContents {
 		fmt.Printf("Template saved structure only (contents ignored)\n")
 	} else {
 		contentFiles := len(m.GetFilesWithContents())
-		fmt.Printf("Template saved with %d files containing content\n", contentFiles)
+		if structureFiles := m.GetFileCount() - contentFiles; structureFiles > 0 {
+			fmt.Printf("Template saved with %d files containing content and %d structure only\n", contentFiles, structureFiles)
+		} else {
+			fmt.Printf("Template saved with %d files containing content\n", contentFiles)
+		}
 		
 		if !noCompression && compressedFiles > 0 {
 			compressionRatio := (1.0 - m.GetCompressionRatio()) * 100
@@ -136,6 +396,96 @@ func runMake(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// reportSecrets lists the likely secrets found in the captured files and returns
+// the error refusing to create the template
+func reportSecrets(findings []secrets.Finding) error {
+	files := make(map[string]bool)
+	fmt.Fprintln(os.Stderr, "Likely secrets found:")
+	for _, f := range findings {
+		files[f.Path] = true
+		fmt.Fprintf(os.Stderr, "  %s:%d: %s (%s)\n", f.Path, f.Line, f.Rule, f.Masked())
+	}
+	fmt.Fprintf(os.Stderr, "Remove or ignore these files, list them in %s, or use --allow-secrets to capture them anyway\n", secrets.AllowFileName)
+
+	return fmt.Errorf("refusing to create template '%s': %d likely secret(s) in %d file(s)", templateName, len(findings), len(files))
+}
+
+// displayRedactedSecrets prints the variables replacing the secrets redacted from
+// the files of a template
+func displayRedactedSecrets(m *manifest.Manifest) {
+	if len(m.Variables) == 0 {
+		return
+	}
+
+	files := 0
+	for _, file := range m.Files {
+		if file.Templated {
+			files++
+		}
+	}
+	fmt.Printf("Redacted secrets in %d file(s) into %d variable(s), supplied on restore:\n", files, len(m.Variables))
+	for _, v := range m.Variables {
+		fmt.Printf("  %s (%s)\n", v.Name, v.Description)
+	}
+}
+
+// displaySkippedFiles prints the files skipped by size and type rules
+func displaySkippedFiles(skipped []skippedFile) {
+	if len(skipped) == 0 {
+		return
+	}
+
+	var size int64
+	for _, file := range skipped {
+		size += file.size
+	}
+	fmt.Printf("Skipped %d file(s) by size or type rules (%s):\n", len(skipped), ignore.FormatSize(size))
+	for _, file := range skipped {
+		fmt.Printf("  %s (%s, %s: %s)\n", file.relPath, ignore.FormatSize(file.size),
+			file.predicate.Source(), file.predicate.Text)
+	}
+}
+
+// displayUpdateChanges prints the files added, modified and removed by a template update
+func displayUpdateChanges(changes []manifest.Change) {
+	added, modified, removed := manifest.CountChanges(changes)
+	if len(changes) == 0 {
+		fmt.Println("No changes since the last update")
+		return
+	}
+
+	fmt.Printf("Changes: %d added, %d modified, %d removed\n", added, modified, removed)
+	for _, change := range changes {
+		switch change.Kind {
+		case manifest.ChangeAdded:
+			fmt.Printf("  + %s\n", change.Path)
+		case manifest.ChangeModified:
+			fmt.Printf("  ~ %s\n", change.Path)
+		case manifest.ChangeRemoved:
+			fmt.Printf("  - %s\n", change.Path)
+		}
+	}
+}
+
+// resolveGitSource resolves the commit of a git repository to capture and returns
+// its source record and the local path of the repository
+func resolveGitSource(repo, ref string) (*manifest.Source, string, error) {
+	repoPath, err := git.RepositoryPath(repo)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+	commit, err := git.ResolveCommit(repoPath, ref)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &manifest.Source{Repository: repoPath, Ref: ref, Commit: commit}, repoPath, nil
+}
+
 // validateTargetDirectory checks if the target directory is valid
 func validateTargetDirectory(targetDir string) error {
 	info, err := os.Stat(targetDir)
@@ -159,7 +509,7 @@ func validateTemplateName(name string) error {
 		return fmt.Errorf("template name cannot be empty")
 	}
 
-	if strings.ContainsAny(name, "/\\:*?\"<>|") {
+	if strings.ContainsAny(name, "/\\:*?\"<>|"+storage.RefSeparator) {
 		return fmt.Errorf("template name contains invalid characters")
 	}
 
@@ -170,24 +520,590 @@ func validateTemplateName(name string) error {
 	return nil
 }
 
-// scanDirectory recursively scans a directory and processes all files
-func scanDirectory(rootDir, currentDir string, m *manifest.Manifest, storage *storage.Storage, ignoreRules *ignore.IgnoreRules) error {
-	return filepath.WalkDir(currentDir, func(path string, d fs.DirEntry, err error) error {
+// validateVersionTag checks if a version tag is valid; an empty tag is allowed
+func validateVersionTag(tag string) error {
+	if strings.ContainsAny(tag, " \t\n"+storage.RefSeparator) {
+		return fmt.Errorf("version tag contains invalid characters")
+	}
+	return nil
+}
+
+// validateJobs checks if the number of concurrent jobs is valid
+func validateJobs(jobs int) error {
+	if jobs < 1 {
+		return fmt.Errorf("number of jobs must be at least 1")
+	}
+	return nil
+}
+
+// validatePlaceholder checks the placeholder selected for structure-only files
+func validatePlaceholder(kind string, lines int) error {
+	switch kind {
+	case manifest.PlaceholderEmpty, manifest.PlaceholderHeader, manifest.PlaceholderSkeleton, manifest.PlaceholderSized:
+	default:
+		return fmt.Errorf("unsupported placeholder: %s (expected empty, header, skeleton or sized)", kind)
+	}
+	if lines < 1 {
+		return fmt.Errorf("--placeholder-lines must be at least 1")
+	}
+	return nil
+}
+
+// scannedFile is a file selected for the template by scanDirectory
+type scannedFile struct {
+	path          string // Path of the file on disk
+	relPath       string // Path of the file relative to the scanned root
+	structureOnly bool   // Whether a contents-ignore pattern matches the file
+}
+
+// skippedFile is a file left out of the template by a size or type rule
+type skippedFile struct {
+	relPath   string // Slash-separated path of the file relative to the scanned root
+	size      int64
+	predicate *ignore.Predicate // Rule that skipped the file
+}
+
+// scanResult reports what was left out of a scanned template
+type scanResult struct {
+	skipped []skippedFile     // Files skipped by size and type rules
+	secrets []secrets.Finding // Likely secrets in files that were not stored
+}
+
+// scanDirectory recursively scans a directory and processes all files, returning
+// those skipped by size and type rules and the secrets found. Files are processed
+// concurrently by makeJobs workers, but are added to the manifest in walk order
+// so that the manifest is deterministic.
+func scanDirectory(rootDir, currentDir string, m *manifest.Manifest, storage *storage.Storage, ignoreRules *ignore.IgnoreRules) (scanResult, error) {
+	files, skipped, err := collectFiles(rootDir, currentDir, ignoreRules)
+	if err != nil {
+		return scanResult{}, err
+	}
+
+	// Process the files
+	entries := make([]manifest.FileEntry, len(files))
+	findings := make([][]secrets.Finding, len(files))
+	err = workpool.Run(len(files), makeJobs, func(i int) error {
+		entry, found, err := processFile(files[i].path, files[i].relPath, !files[i].structureOnly, storage)
+		if err != nil {
+			return err
+		}
+		entries[i], findings[i] = entry, found
+		return nil
+	})
+	if err != nil {
+		return scanResult{}, err
+	}
+
+	// Redact in walk order so that the variables are numbered deterministically
+	result := scanResult{skipped: skipped}
+	for i, entry := range entries {
+		if len(findings[i]) > 0 {
+			if secretRedactor == nil {
+				result.secrets = append(result.secrets, findings[i]...)
+				continue
+			}
+			if entry, err = redactFile(files[i], findings[i], storage); err != nil {
+				return scanResult{}, err
+			}
+		}
+		m.AddEntry(entry)
+	}
+
+	return result, nil
+}
+
+// scanArchive processes the regular files of an archive, read sequentially by walk.
+// Files are matched against the default and preset patterns, the extra patterns and the
+// .tmpltrignore and .tmpltrinclude files in the archive. As the archive is read
+// in a single pass, files preceding these files are only filtered out of the
+// manifest once they have been read, and files they would re-include cannot be
+// captured, which is reported as a warning. Size and type rules and the secrets
+// allowlist are applied as files are read, so those of a file in the archive only
+// apply to the files following it. A file stored several times in the archive is
+// captured with its last contents. The files skipped by size and type rules and
+// the secrets found are returned.
+func scanArchive(walk func(archive.WalkFunc) error, m *manifest.Manifest, storage *storage.Storage, opts ignoreOptions) (scanResult, error) {
+	if opts.autoPreset {
+		return scanResult{}, fmt.Errorf("--auto-preset requires a target directory; name the presets with --preset")
+	}
+
+	ignoreRules := ignore.NewIgnoreRules(".")
+	if err := addBaseIgnoreRules(ignoreRules, opts); err != nil {
+		return scanResult{}, err
+	}
+	ignoreRules.AddPatterns(opts.patterns, ignoreFilesSource)
+	ignoreRules.AddIncludePatterns(opts.includes, includeSource)
+	ignoreRules.AddContentsPatterns(opts.contents, contentsIgnoreSource)
+
+	var entries []manifest.FileEntry
+	index := make(map[string]int)
+	explained := make(map[string]bool)
+	var skipped []string
+	skippedBySize := make(map[string]skippedFile)
+	secretsByName := make(map[string][]secrets.Finding)
+	err := walk(func(file archive.Entry, r io.Reader) error {
+		if path.Base(file.Name) == ignore.IgnoreFileName {
+			dir := path.Dir(file.Name)
+			if dir == "." {
+				dir = ""
+			}
+			if err := ignoreRules.LoadIgnoreReader(dir, r); err != nil {
+				return fmt.Errorf("failed to load ignore file: %w", err)
+			}
+			return nil
+		}
+		if file.Name == ignore.IncludeFileName {
+			if err := ignoreRules.LoadIncludeReader(r); err != nil {
+				return fmt.Errorf("failed to load include file: %w", err)
+			}
+			return nil
+		}
+		if file.Name == secrets.AllowFileName {
+			if secretAllowlist != nil {
+				allowlist, err := secrets.ReadAllowlist(r)
+				if err != nil {
+					return err
+				}
+				secretAllowlist = allowlist
+			}
+			return nil
+		}
+		if ignored, err := archivePathIgnored(ignoreRules, file.Name, explained); ignored || err != nil {
+			skipped = append(skipped, file.Name)
+			return err
+		}
+
+		// Peek at the contents for type rules without consuming them
+		br := bufio.NewReaderSize(r, ignore.HeadSize)
+		decision, err := ignoreRules.ExplainFile(file.Name, file.Size, func() ([]byte, error) {
+			head, err := br.Peek(ignore.HeadSize)
+			if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+				return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+			}
+			return head, nil
+		})
+		if err != nil {
+			return err
+		}
+		if decision.Ignored {
+			if explainIgnores {
+				printIgnoreDecision(file.Name, false, decision)
+			}
+			skippedBySize[file.Name] = skippedFile{relPath: file.Name, size: file.Size, predicate: decision.Predicate}
+			return nil
+		}
+		delete(skippedBySize, file.Name)
+		delete(secretsByName, file.Name)
+
+		structureOnly, err := explainContents(ignoreRules, file.Name, file.Name)
+		if err != nil {
+			return err
+		}
+
+		entry, found, err := processArchiveFile(file, br, !structureOnly, storage)
+		if err != nil {
+			return err
+		}
+		if len(found) > 0 {
+			secretsByName[file.Name] = found
+			return nil
+		}
+
+		if i, ok := index[entry.OriginalPath]; ok {
+			entries[i] = entry
+		} else {
+			index[entry.OriginalPath] = len(entries)
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return scanResult{}, err
+	}
+
+	// The last copy of a file in the archive decides whether it is skipped
+	for _, entry := range entries {
+		name := filepath.ToSlash(entry.OriginalPath)
+		if _, ok := skippedBySize[name]; ok {
+			continue
+		}
+		if _, ok := secretsByName[name]; ok {
+			continue
+		}
+		ignored, err := archivePathIgnored(ignoreRules, name, explained)
+		if err != nil {
+			return scanResult{}, err
+		}
+		if !ignored {
+			m.AddEntry(entry)
+		}
+	}
+
+	// Files skipped before a later ignore or include file re-included them cannot be recovered
+	missed := 0
+	for _, name := range skipped {
+		decision, err := ignoreRules.ExplainPath(name, false)
+		if err != nil {
+			return scanResult{}, err
+		}
+		if !decision.Ignored && m.GetFileByPath(filepath.FromSlash(name)) == nil {
+			missed++
+		}
+	}
+	if missed > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d file(s) were skipped before a later %s or %s file in the archive included them; store those files first in the archive to capture them\n",
+			missed, ignore.IgnoreFileName, ignore.IncludeFileName)
+	}
+
+	var result scanResult
+	for _, file := range skippedBySize {
+		result.skipped = append(result.skipped, file)
+	}
+	sort.Slice(result.skipped, func(i, j int) bool { return result.skipped[i].relPath < result.skipped[j].relPath })
+
+	// Secrets in files that a later ignore file excluded do not matter
+	for name, found := range secretsByName {
+		decision, err := ignoreRules.ExplainPath(name, false)
+		if err != nil {
+			return scanResult{}, err
+		}
+		if !decision.Ignored {
+			result.secrets = append(result.secrets, found...)
+		}
+	}
+	sort.SliceStable(result.secrets, func(i, j int) bool { return result.secrets[i].Path < result.secrets[j].Path })
+	return result, nil
+}
+
+// archivePathIgnored checks if an archive file or any of its parent directories
+// is ignored, mirroring how a directory walk skips ignored directories. With
+// --explain-ignores, the decision is printed once per path or ignored directory,
+// recording what was printed in explained.
+func archivePathIgnored(ignoreRules *ignore.IgnoreRules, name string, explained map[string]bool) (bool, error) {
+	decision, err := ignoreRules.ExplainPath(name, false)
+	if err != nil {
+		return false, err
+	}
+
+	if explainIgnores && decision.Pattern != nil {
+		// Explain an ignored parent directory itself rather than each file below it
+		explainedPath, isDir, parentDecision := name, false, decision
+		if decision.Parent != "" {
+			explainedPath, isDir, parentDecision.Parent = decision.Parent, true, ""
+		}
+		if !explained[explainedPath] {
+			explained[explainedPath] = true
+			printIgnoreDecision(explainedPath, isDir, parentDecision)
+		}
+	}
+
+	return decision.Ignored, nil
+}
+
+// processArchiveFile processes a single archive file for the template and returns
+// its manifest entry. Its contents are saved if includeContents is set and
+// --ignore-contents is not, and its placeholder otherwise, unless they contain
+// secrets, which are returned instead or, with --redact-secrets, replaced by
+// variable placeholders.
+func processArchiveFile(file archive.Entry, r io.Reader, includeContents bool, storage *storage.Storage) (manifest.FileEntry, []secrets.Finding, error) {
+	relativePath := filepath.FromSlash(file.Name)
+	includeContents = includeContents && !ignoreContents
+	entry := manifest.FileEntry{
+		OriginalPath:    relativePath,
+		IncludeContents: includeContents,
+		OriginalSize:    file.Size,
+		Mode:            uint32(file.Mode),
+	}
+
+	if !includeContents {
+		// Archive files are processed in order, so secrets can be redacted right away
+		open := func() (io.ReadCloser, error) { return io.NopCloser(r), nil }
+		placeholder, found, err := storePlaceholder(relativePath, file.Size, open, secretRedactor != nil, storage)
+		if err != nil || len(found) > 0 {
+			return manifest.FileEntry{}, found, err
+		}
+		placeholder.Mode = entry.Mode
+		return placeholder, nil, nil
+	}
+
+	// Scan a copy of the contents so that secrets never reach the store
+	if secretAllowlist != nil && !secretAllowlist.Allows(file.Name) {
+		spooled, found, err := spoolAndScan(file.Name, r)
+		if err != nil {
+			return manifest.FileEntry{}, nil, err
+		}
+		defer func() {
+			spooled.Close()
+			os.Remove(spooled.Name())
+		}()
+		if len(found) > 0 {
+			if secretRedactor == nil {
+				return manifest.FileEntry{}, found, nil
+			}
+			entry, err := storeRedacted(spooled, found, entry, storage)
+			return entry, nil, err
+		}
+		r = spooled
+	}
+
+	compress := !noCompression && compression.ShouldCompressFile(file.Size, relativePath)
+	blob, err := storage.SaveStream(templateName, r, compress)
+	if err != nil {
+		return manifest.FileEntry{}, nil, fmt.Errorf("failed to save file %s to storage: %w", relativePath, err)
+	}
+
+	entry.Hash = blob.Hash
+	entry.Compressed = blob.Compressed
+	entry.OriginalSize = blob.OriginalSize
+	entry.StoredSize = blob.StoredSize
+	return entry, nil, nil
+}
+
+// redactFile stores the contents of a file, or the placeholder of a structure-only
+// file, with the secrets found in them replaced by variable placeholders and
+// returns its manifest entry
+func redactFile(scanned scannedFile, found []secrets.Finding, storage *storage.Storage) (manifest.FileEntry, error) {
+	filePath, relativePath := scanned.path, scanned.relPath
+	file, err := os.Open(filePath)
+	if err != nil {
+		return manifest.FileEntry{}, fmt.Errorf("failed to read file %s: %w", relativePath, err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return manifest.FileEntry{}, fmt.Errorf("failed to get file info for %s: %w", relativePath, err)
+	}
+
+	if scanned.structureOnly || ignoreContents {
+		open := func() (io.ReadCloser, error) { return io.NopCloser(file), nil }
+		entry, _, err := storePlaceholder(relativePath, fileInfo.Size(), open, true, storage)
+		entry.Mode = uint32(fileInfo.Mode().Perm())
+		return entry, err
+	}
+
+	return storeRedacted(file, found, manifest.FileEntry{
+		OriginalPath:    relativePath,
+		IncludeContents: true,
+		Mode:            uint32(fileInfo.Mode().Perm()),
+	}, storage)
+}
+
+// storeRedacted stores contents read from r with the secrets found in them
+// replaced by variable placeholders, completing the file's manifest entry
+func storeRedacted(r io.Reader, found []secrets.Finding, entry manifest.FileEntry, storage *storage.Storage) (manifest.FileEntry, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return manifest.FileEntry{}, fmt.Errorf("failed to read file %s: %w", entry.OriginalPath, err)
+	}
+	redacted := secretRedactor.Redact(content, found)
+
+	compress := !noCompression && compression.ShouldCompressFile(int64(len(redacted)), entry.OriginalPath)
+	blob, err := storage.SaveStream(templateName, bytes.NewReader(redacted), compress)
+	if err != nil {
+		return manifest.FileEntry{}, fmt.Errorf("failed to save file %s to storage: %w", entry.OriginalPath, err)
+	}
+
+	entry.Hash = blob.Hash
+	entry.Compressed = blob.Compressed
+	entry.OriginalSize = blob.OriginalSize
+	entry.StoredSize = blob.StoredSize
+	entry.Templated = true
+	return entry, nil
+}
+
+// spoolAndScan copies an archive file to a temporary file while scanning it for
+// secrets, returning the temporary file positioned at its start
+func spoolAndScan(name string, r io.Reader) (*os.File, []secrets.Finding, error) {
+	tmp, err := os.CreateTemp("", "tmpltr-scan-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	// The scan stops early on binary contents, so copy whatever it left
+	tee := io.TeeReader(r, tmp)
+	found, err := secrets.Scan(name, tee)
+	if err == nil {
+		_, err = io.Copy(io.Discard, tee)
+	}
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, nil, fmt.Errorf("failed to scan %s for secrets: %w", name, err)
+	}
+	return tmp, found, nil
+}
+
+// ignoreOptions are the command-line options selecting the files to capture
+type ignoreOptions struct {
+	patterns   []string // Extra ignore patterns
+	includes   []string // Extra include patterns
+	gitIgnore  bool     // Whether .gitignore files are honored
+	noDefaults bool     // Whether the built-in default patterns are disabled
+	presets    []string // Names of the ecosystem presets to apply
+	autoPreset bool     // Whether presets are detected from the directory's marker files
+	maxSize    string   // Size above which files are skipped, "" for no limit
+	noBinary   bool     // Whether binary files are skipped
+	contents   []string // Extra patterns of files captured as structure only
+}
+
+// makeIgnoreOptions returns the ignore options given to the make command
+func makeIgnoreOptions() ignoreOptions {
+	return ignoreOptions{
+		patterns:   ignoreFiles,
+		includes:   includeFiles,
+		noDefaults: noDefaultIgnore,
+		presets:    makePresets,
+		autoPreset: makeAutoPreset,
+		maxSize:    maxFileSize,
+		noBinary:   excludeBinary,
+		contents:   contentsIgnore,
+	}
+}
+
+// addBaseIgnoreRules adds tmpltr's own files, which are always ignored, and the
+// patterns with the lowest precedence: the default patterns unless disabled and
+// the named presets, as well as the size and type rules given on the command line
+func addBaseIgnoreRules(ignoreRules *ignore.IgnoreRules, opts ignoreOptions) error {
+	ignoreRules.AddControlPatterns()
+	if !opts.noDefaults {
+		ignoreRules.AddDefaultPatterns()
+	}
+
+	for _, name := range opts.presets {
+		if err := ignoreRules.AddPreset(strings.TrimSpace(name)); err != nil {
+			return err
+		}
+	}
+
+	if opts.maxSize != "" {
+		if err := ignoreRules.AddMaxFileSize(opts.maxSize, maxFileSizeSource); err != nil {
+			return err
+		}
+	}
+	if opts.noBinary {
+		ignoreRules.AddExcludeBinary(excludeBinarySource)
+	}
+	return nil
+}
+
+// setupIgnoreRules creates the ignore rules used when scanning a directory:
+// the default and preset patterns, the directory's .tmpltrignore files and extra
+// patterns, the include patterns, and optionally the .gitignore files of the directory
+func setupIgnoreRules(rootDir string, opts ignoreOptions) (*ignore.IgnoreRules, error) {
+	ignoreRules := ignore.NewIgnoreRules(rootDir)
+
+	// Detect the presets of the ecosystems in the directory
+	if opts.autoPreset {
+		detected, err := ignore.DetectPresets(rootDir)
+		if err != nil {
+			return nil, err
+		}
+		opts.presets = mergePresets(opts.presets, detected)
+	}
+
+	// Add default and preset ignore patterns
+	if err := addBaseIgnoreRules(ignoreRules, opts); err != nil {
+		return nil, err
+	}
+
+	// Load .tmpltrignore file if it exists
+	if err := ignoreRules.LoadIgnoreFile(); err != nil {
+		return nil, fmt.Errorf("failed to load ignore file: %w", err)
+	}
+
+	// Add command-line ignore patterns
+	ignoreRules.AddPatterns(opts.patterns, ignoreFilesSource)
+
+	// Load .tmpltrinclude file if it exists, and command-line include patterns
+	if err := ignoreRules.LoadIncludeFile(); err != nil {
+		return nil, fmt.Errorf("failed to load include file: %w", err)
+	}
+	ignoreRules.AddIncludePatterns(opts.includes, includeSource)
+	ignoreRules.AddContentsPatterns(opts.contents, contentsIgnoreSource)
+
+	if opts.gitIgnore {
+		if err := ignoreRules.EnableGitIgnore(); err != nil {
+			return nil, fmt.Errorf("failed to load gitignore rules: %w", err)
+		}
+	}
+
+	return ignoreRules, nil
+}
+
+// mergePresets appends the detected presets not already named to the given ones
+func mergePresets(named, detected []string) []string {
+	merged := append([]string{}, named...)
+	for _, name := range detected {
+		found := false
+		for _, n := range named {
+			if strings.TrimSpace(n) == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, name)
+		}
+	}
+	return merged
+}
+
+// completePresets completes the names of the ecosystem presets
+func completePresets(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return ignore.PresetNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// resolveGitIgnore decides whether .gitignore files are honored: the --gitignore
+// flag if given, otherwise the default from the configuration file
+func resolveGitIgnore(cmd *cobra.Command, flag bool, store *storage.Storage) (bool, error) {
+	if cmd.Flags().Changed("gitignore") {
+		return flag, nil
+	}
+
+	cfg, err := config.Load(store.GetConfigPath())
+	if err != nil {
+		return false, err
+	}
+	return cfg.GitIgnore, nil
+}
+
+// collectFiles walks a directory and returns the files not excluded by the ignore
+// rules, and separately those skipped by size and type rules
+func collectFiles(rootDir, currentDir string, ignoreRules *ignore.IgnoreRules) ([]scannedFile, []skippedFile, error) {
+	var files []scannedFile
+	var skipped []skippedFile
+	err := filepath.WalkDir(currentDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return fmt.Errorf("error walking directory: %w", err)
 		}
 
+		// Check if the file or directory should be ignored
+		decision, err := ignoreRules.Explain(path, d.IsDir())
+		if err != nil {
+			return err
+		}
+		ignored := decision.Ignored
+
+		if explainIgnores && decision.Pattern != nil {
+			if relPath, err := filepath.Rel(rootDir, path); err == nil {
+				printIgnoreDecision(filepath.ToSlash(relPath), d.IsDir(), decision)
+			}
+		}
+
 		// Skip directories
 		if d.IsDir() {
-			// Check if directory should be ignored
-			if ignoreRules.ShouldIgnore(path) {
+			if ignored {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
 		// Check if file should be ignored
-		if ignoreRules.ShouldIgnore(path) {
+		if ignored {
 			return nil
 		}
 
@@ -197,79 +1113,247 @@ func scanDirectory(rootDir, currentDir string, m *manifest.Manifest, storage *st
 			return fmt.Errorf("failed to calculate relative path: %w", err)
 		}
 
-		// Process the file
-		return processFile(path, relPath, m, storage)
+		// Check the size and type rules, following symbolic links as processFile does
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to get file info for %s: %w", relPath, err)
+		}
+		decision, err = ignoreRules.ExplainFile(path, info.Size(), func() ([]byte, error) {
+			return ignore.ReadHead(path)
+		})
+		if err != nil {
+			return err
+		}
+		if decision.Ignored {
+			if explainIgnores {
+				printIgnoreDecision(filepath.ToSlash(relPath), false, decision)
+			}
+			skipped = append(skipped, skippedFile{relPath: filepath.ToSlash(relPath), size: info.Size(), predicate: decision.Predicate})
+			return nil
+		}
+
+		structureOnly, err := explainContents(ignoreRules, path, filepath.ToSlash(relPath))
+		if err != nil {
+			return err
+		}
+
+		files = append(files, scannedFile{path: path, relPath: relPath, structureOnly: structureOnly})
+		return nil
 	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return files, skipped, nil
 }
 
-// processFile processes a single file for the template
-func processFile(filePath, relativePath string, m *manifest.Manifest, storage *storage.Storage) error {
-	var fileHash string
-	var err error
-	var compressed bool
-	var originalSize, storedSize int64
+// explainContents decides whether a file is captured as structure only, printing
+// the deciding contents-ignore pattern with --explain-ignores
+func explainContents(ignoreRules *ignore.IgnoreRules, filePath, relPath string) (bool, error) {
+	structureOnly, pattern, err := ignoreRules.ExplainContents(filePath)
+	if err != nil {
+		return false, err
+	}
+	if explainIgnores && pattern != nil {
+		fmt.Printf("  %s: %s\n", relPath, describeContentsDecision(structureOnly, pattern))
+	}
+	return structureOnly, nil
+}
 
+// processFile processes a single file for the template and returns its manifest
+// entry. Its contents are saved if includeContents is set and --ignore-contents
+// is not, and its placeholder otherwise, unless they contain secrets, which are
+// returned instead.
+func processFile(filePath, relativePath string, includeContents bool, storage *storage.Storage) (manifest.FileEntry, []secrets.Finding, error) {
 	// Get file info for size
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to get file info for %s: %w", relativePath, err)
+		return manifest.FileEntry{}, nil, fmt.Errorf("failed to get file info for %s: %w", relativePath, err)
 	}
-	originalSize = fileInfo.Size()
 
-	if ignoreContents {
-		// Generate hash based on file path for ignore-contents mode
-		fileHash = hash.GenerateFileNameHash(relativePath)
-		storedSize = 0
-		
-		// Create empty file in storage if it doesn't exist
-		if !storage.FileExists(templateName, fileHash) {
-			if err := storage.SaveFile(templateName, fileHash, []byte("")); err != nil {
-				return fmt.Errorf("failed to save empty file placeholder: %w", err)
-			}
+	includeContents = includeContents && !ignoreContents
+	if !includeContents {
+		// Secrets in the placeholder are redacted by scanDirectory, in walk order
+		open := func() (io.ReadCloser, error) { return os.Open(filePath) }
+		entry, found, err := storePlaceholder(relativePath, fileInfo.Size(), open, false, storage)
+		if err != nil || len(found) > 0 {
+			return manifest.FileEntry{}, found, err
 		}
-	} else {
-		// Read file content
-		content, err := os.ReadFile(filePath)
+		entry.Mode = uint32(fileInfo.Mode().Perm())
+		return entry, nil, nil
+	}
+
+	scan := secretAllowlist != nil && !secretAllowlist.Allows(filepath.ToSlash(relativePath))
+	blob, found, err := storeFileContents(filePath, relativePath, fileInfo, scan, storage)
+	if err != nil || len(found) > 0 {
+		return manifest.FileEntry{}, found, err
+	}
+
+	return manifest.FileEntry{
+		OriginalPath:    relativePath,
+		Hash:            blob.Hash,
+		IncludeContents: true,
+		Compressed:      blob.Compressed,
+		OriginalSize:    blob.OriginalSize,
+		StoredSize:      blob.StoredSize,
+		Mode:            uint32(fileInfo.Mode().Perm()),
+	}, nil, nil
+}
+
+// storePlaceholder stores the placeholder of a structure-only file selected by
+// --placeholder and returns its manifest entry. Header and skeleton placeholders
+// are read through open and scanned for secrets like file contents; the secrets
+// found are returned unless redact is set and they are redacted.
+func storePlaceholder(relativePath string, size int64, open func() (io.ReadCloser, error), redact bool, storage *storage.Storage) (manifest.FileEntry, []secrets.Finding, error) {
+	entry := manifest.FileEntry{OriginalPath: relativePath, OriginalSize: size}
+
+	var content []byte
+	switch placeholderKind {
+	case manifest.PlaceholderHeader, manifest.PlaceholderSkeleton:
+		r, err := open()
 		if err != nil {
-			return fmt.Errorf("failed to read file %s: %w", relativePath, err)
+			return manifest.FileEntry{}, nil, fmt.Errorf("failed to read file %s: %w", relativePath, err)
 		}
+		content, entry.Placeholder, err = placeholderContent(relativePath, r)
+		r.Close()
+		if err != nil {
+			return manifest.FileEntry{}, nil, err
+		}
+	case manifest.PlaceholderSized:
+		entry.Placeholder = manifest.PlaceholderSized
+	}
 
-		// Calculate actual file content hash
-		fileHash = hash.HashBytes(content)
+	if content == nil {
+		// Generate hash based on file path for structure-only files
+		entry.Hash = hash.GenerateFileNameHash(relativePath)
 
-		// Save file to storage if it doesn't already exist (deduplication)
-		if !storage.FileExists(templateName, fileHash) {
-			if noCompression {
-				// Save without compression
-				if err := storage.SaveFile(templateName, fileHash, content); err != nil {
-					return fmt.Errorf("failed to save file %s to storage: %w", relativePath, err)
-				}
-				compressed = false
-				storedSize = originalSize
-			} else {
-				// Save with optional compression
-				isCompressed, storedBytes, err := storage.SaveFileWithCompression(templateName, fileHash, relativePath, content)
-				if err != nil {
-					return fmt.Errorf("failed to save file %s to storage: %w", relativePath, err)
-				}
-				compressed = isCompressed
-				storedSize = storedBytes
+		// Create empty file in storage if it doesn't exist
+		if !storage.FileExists(templateName, entry.Hash) {
+			if err := storage.SaveFile(templateName, entry.Hash, []byte("")); err != nil {
+				return manifest.FileEntry{}, nil, fmt.Errorf("failed to save empty file placeholder: %w", err)
 			}
-		} else {
-			// File already exists, we need to determine if it was compressed
-			// For deduplication, we'll assume the same compression settings
-			storedSize = originalSize
-			compressed = false
-			if !noCompression {
-				// This is a simplified approach - in a real implementation,
-				// we might want to store compression info separately
-				compressed = true
+		}
+		return entry, nil, nil
+	}
+
+	// Scan the placeholder before storing it so that secrets never reach the store
+	slashPath := filepath.ToSlash(relativePath)
+	if secretAllowlist != nil && !secretAllowlist.Allows(slashPath) {
+		found, err := secrets.Scan(slashPath, bytes.NewReader(content))
+		if err != nil {
+			return manifest.FileEntry{}, nil, err
+		}
+		if len(found) > 0 {
+			if !redact || secretRedactor == nil {
+				return manifest.FileEntry{}, found, nil
 			}
+			content = secretRedactor.Redact(content, found)
+			entry.Templated = true
 		}
 	}
 
-	// Add file to manifest
-	m.AddFile(relativePath, fileHash, !ignoreContents, compressed, originalSize, storedSize)
+	compress := !noCompression && compression.ShouldCompressFile(int64(len(content)), relativePath)
+	blob, err := storage.SaveStream(templateName, bytes.NewReader(content), compress)
+	if err != nil {
+		return manifest.FileEntry{}, nil, fmt.Errorf("failed to save placeholder of %s to storage: %w", relativePath, err)
+	}
+	entry.Hash = blob.Hash
+	entry.Compressed = blob.Compressed
+	entry.StoredSize = blob.StoredSize
+	return entry, nil, nil
+}
+
+// placeholderContent returns the header or skeleton placeholder of a file and
+// the kind of placeholder it is. Skeletons are made of Go files that parse,
+// other files get a header, and binary files no placeholder at all.
+func placeholderContent(relativePath string, r io.Reader) ([]byte, string, error) {
+	if placeholderKind == manifest.PlaceholderSkeleton && path.Ext(relativePath) == ".go" {
+		src, err := io.ReadAll(r)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read file %s: %w", relativePath, err)
+		}
+		if skeleton, err := placeholder.GoSkeleton(src); err == nil {
+			return skeleton, manifest.PlaceholderSkeleton, nil
+		}
+		r = bytes.NewReader(src)
+	}
 
-	return nil
-}
\ No newline at end of file
+	header, err := placeholder.Header(r, headerLines)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file %s: %w", relativePath, err)
+	}
+	if header == nil {
+		return nil, "", nil
+	}
+	return header, manifest.PlaceholderHeader, nil
+}
+
+// storeFileContents stores the contents of a file in the template and returns the
+// resulting blob. If scan is set, the contents are first scanned for secrets, and
+// the secrets found are returned instead so that they never reach the store. If
+// the hash cache shows the file is unchanged since it was last stored in this
+// template, and found free of secrets when scanning is needed, the file is not
+// read at all.
+func storeFileContents(filePath, relativePath string, fileInfo os.FileInfo, scan bool, store *storage.Storage) (storage.BlobInfo, []secrets.Finding, error) {
+	var absPath string
+	var cached cache.Entry
+	var hit bool
+	if hashCache != nil {
+		var err error
+		if absPath, err = filepath.Abs(filePath); err != nil {
+			return storage.BlobInfo{}, nil, fmt.Errorf("failed to resolve path of %s: %w", relativePath, err)
+		}
+		cached, hit = hashCache.Lookup(absPath, fileInfo)
+	}
+
+	if scan && !cached.SecretsClean {
+		found, err := secrets.ScanFile(filePath, filepath.ToSlash(relativePath))
+		if err != nil || len(found) > 0 {
+			return storage.BlobInfo{}, found, err
+		}
+		if hit {
+			cached.SecretsClean = true
+			hashCache.Store(absPath, fileInfo, cached)
+		}
+	}
+
+	if hit {
+		if blob, ok := store.LookupBlob(templateName, cached.Hash); ok {
+			return blob, nil, nil
+		}
+		if cached.Template == templateName && store.FileExists(templateName, cached.Hash) {
+			return storage.BlobInfo{
+				Hash:         cached.Hash,
+				Compressed:   cached.Compressed,
+				OriginalSize: cached.Size,
+				StoredSize:   cached.StoredSize,
+			}, nil, nil
+		}
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return storage.BlobInfo{}, nil, fmt.Errorf("failed to read file %s: %w", relativePath, err)
+	}
+	defer file.Close()
+
+	// Hash, compress and store the content in a single streaming pass;
+	// content that is already stored is deduplicated by the storage
+	compress := !noCompression && compression.ShouldCompressFile(fileInfo.Size(), relativePath)
+	blob, err := store.SaveStream(templateName, file, compress)
+	if err != nil {
+		return storage.BlobInfo{}, nil, fmt.Errorf("failed to save file %s to storage: %w", relativePath, err)
+	}
+
+	if hashCache != nil {
+		hashCache.Store(absPath, fileInfo, cache.Entry{
+			Hash:         blob.Hash,
+			Template:     templateName,
+			Compressed:   blob.Compressed,
+			StoredSize:   blob.StoredSize,
+			SecretsClean: scan || cached.SecretsClean,
+		})
+	}
+
+	return blob, nil, nil
+}
@@ -1,20 +1,42 @@
 package cmd
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"tmpltr/internal/bundle"
 	"tmpltr/internal/manifest"
+	"tmpltr/internal/project"
+	"tmpltr/internal/prompt"
 	"tmpltr/internal/storage"
+	"tmpltr/internal/workpool"
+)
+
+// Output formats of the restore command
+const (
+	restoreFormatDir   = "dir"
+	restoreFormatTar   = "tar"
+	restoreFormatTarGz = "tar.gz"
 )
 
 var (
 	restoreTemplateName string
 	outputDirectory     string
+	restoreJobs         int
+	restoreFrom         string
+	restoreFormat       string
 )
 
 // restoreCmd represents the restore command
@@ -24,19 +46,53 @@ var restoreCmd = &cobra.Command{
 	Long: `Restore a saved template by recreating its directory structure and files
 at the specified output location.
 
+The latest version of the template is restored unless a version is selected
+with "name@version", where version is a tag, a version number or a date.
+The restored template and version are recorded in .tmpltr.json so that the
+project can later be brought up to date with 'tmpltr update'.
+
+With --from, the template is read from an archive created by 'tmpltr export',
+from a template directory, or from a template store directory (which requires
+--name), without importing it into the local store.
+
+With --format=tar or --format=tar.gz, the template is written as a tar stream
+to the output file, or to stdout if the output is "-", instead of a directory.
+
+Files saved as structure only are restored as the placeholders chosen with
+make --placeholder: empty files, the stored headers or Go skeletons, or sparse
+files of the original size.
+
+Templates made with --redact-secrets hold variables in place of secrets. Each
+value is read from the environment variable of the same name, such as
+Secret_DB_PASSWORD, or prompted for on a terminal without being echoed. The
+values are written into the restored files only and are never recorded.
+
 Example:
-  tmpltr restore --name="my-template" --output="./restored-project"`,
+  tmpltr restore --name="my-template" --output="./restored-project"
+  tmpltr restore --name="my-template@v2" --output="./restored-project"
+  tmpltr restore --name="my-template@2026-09-01" --output="./restored-project"
+  tmpltr restore --name="my-template" --output="./restored-project" --jobs=8
+  tmpltr restore --from="./my-template.tar.gz" --output="./restored-project"
+  tmpltr restore --from="/mnt/share/templates/my-template" --output="./restored-project"
+  tmpltr restore --from="/mnt/share/templates" --name="my-template@v2" --output="./restored-project"
+  tmpltr restore --name="my-template" --format=tar --output=- | docker build -
+  Secret_DB_PASSWORD=... tmpltr restore --name="with-config" --output="./restored-project"`,
 	RunE: runRestore,
 }
 
 func init() {
-	restoreCmd.Flags().StringVarP(&restoreTemplateName, "name", "n", "", "Name of the template to restore (required)")
-	restoreCmd.Flags().StringVarP(&outputDirectory, "output", "o", "", "Output directory path (required)")
-	restoreCmd.MarkFlagRequired("name")
+	restoreCmd.Flags().StringVarP(&restoreTemplateName, "name", "n", "", "Name of the template to restore (required unless --from is given)")
+	restoreCmd.Flags().StringVarP(&outputDirectory, "output", "o", "", "Output directory path, or archive file or \"-\" for stdout with --format=tar (required)")
+	restoreCmd.Flags().IntVarP(&restoreJobs, "jobs", "j", runtime.GOMAXPROCS(0), "Number of files to write concurrently")
+	restoreCmd.Flags().StringVar(&restoreFormat, "format", restoreFormatDir, "Output format: dir, tar or tar.gz")
+	restoreCmd.Flags().StringVar(&restoreFrom, "from", "", "Restore from an exported bundle or a template directory instead of the local store")
 	restoreCmd.MarkFlagRequired("output")
 	
 	// Add completion for template names and directories
 	restoreCmd.RegisterFlagCompletionFunc("name", templateNameCompletion)
+	restoreCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{restoreFormatDir, restoreFormatTar, restoreFormatTarGz}, cobra.ShellCompDirectiveNoFileComp
+	})
 	restoreCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return nil, cobra.ShellCompDirectiveFilterDirs
 	})
@@ -49,65 +105,323 @@ func runRestore(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if err := validateRestoreFormat(restoreFormat); err != nil {
+		return err
+	}
+
 	// Validate output directory
-	if err := validateOutputDirectory(outputDirectory); err != nil {
+	if restoreFormat == restoreFormatDir {
+		if err := validateOutputDirectory(outputDirectory); err != nil {
+			return err
+		}
+	}
+
+	if err := validateJobs(restoreJobs); err != nil {
 		return err
 	}
 
-	// Initialize storage
-	storage, err := storage.NewStorage("")
+	// Open the storage holding the template and load the selected version
+	storage, name, m, cleanup, err := openRestoreSource()
 	if err != nil {
-		return fmt.Errorf("failed to initialize storage: %w", err)
+		return err
 	}
+	defer cleanup()
 
-	// Check if template exists
-	if !storage.TemplateExists(restoreTemplateName) {
-		return fmt.Errorf("template '%s' does not exist", restoreTemplateName)
+	// Refuse manifests with paths leaving the output before writing anything
+	for _, fileEntry := range m.Files {
+		if err := manifest.ValidatePath(fileEntry.OriginalPath); err != nil {
+			return fmt.Errorf("invalid template manifest: %w", err)
+		}
 	}
 
-	// Load manifest
-	m, err := storage.LoadManifest(restoreTemplateName)
+	// Resolve the variables before writing anything
+	values, err := resolveVariables(m, nil)
 	if err != nil {
-		return fmt.Errorf("failed to load template manifest: %w", err)
+		return err
 	}
 
-	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(outputDirectory, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	// Record the template version so later versions can be applied with update
+	record := &project.Record{
+		Template:   name,
+		Version:    m.Version,
+		Variables:  recordedVariables(m, values),
+		RestoredAt: time.Now().UTC(),
 	}
 
-	// Restore files
-	restoredCount := 0
-	for _, fileEntry := range m.Files {
-		if err := restoreFile(fileEntry, outputDirectory, storage); err != nil {
-			return fmt.Errorf("failed to restore file %s: %w", fileEntry.OriginalPath, err)
+	// Messages go to stderr when the archive is streamed to stdout
+	messages := os.Stdout
+
+	if restoreFormat == restoreFormatDir {
+		// Create output directory if it doesn't exist
+		if err := os.MkdirAll(outputDirectory, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+
+		// Restore files concurrently
+		err = workpool.Run(len(m.Files), restoreJobs, func(i int) error {
+			fileEntry := m.Files[i]
+			if err := restoreFile(name, fileEntry, outputDirectory, values, storage); err != nil {
+				return fmt.Errorf("failed to restore file %s: %w", fileEntry.OriginalPath, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := project.Save(outputDirectory, record); err != nil {
+			return err
+		}
+	} else {
+		out := os.Stdout
+		if outputDirectory == "-" {
+			messages = os.Stderr
+		} else {
+			file, err := os.OpenFile(outputDirectory, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer file.Close()
+			out = file
+		}
+
+		if err := restoreToArchive(out, restoreFormat == restoreFormatTarGz, name, m, values, record, storage); err != nil {
+			return err
 		}
-		restoredCount++
 	}
+	restoredCount := len(m.Files)
 
-	fmt.Printf("Successfully restored template '%s' with %d files to: %s\n", 
-		restoreTemplateName, restoredCount, outputDirectory)
+	fmt.Fprintf(messages, "Successfully restored template '%s' (version %d) with %d files to: %s\n", 
+		name, m.Version, restoredCount, outputDirectory)
 	
 	contentFiles := len(m.GetFilesWithContents())
-	emptyFiles := restoredCount - contentFiles
 	if contentFiles > 0 {
-		fmt.Printf("Restored %d files with content\n", contentFiles)
+		fmt.Fprintf(messages, "Restored %d files with content\n", contentFiles)
 	}
-	if emptyFiles > 0 {
-		fmt.Printf("Created %d empty placeholder files\n", emptyFiles)
+	placeholders := make(map[string]int)
+	for _, file := range m.Files {
+		if !file.IncludeContents {
+			kind := file.Placeholder
+			if kind == "" {
+				kind = manifest.PlaceholderEmpty
+			}
+			placeholders[kind]++
+		}
+	}
+	if emptyFiles := placeholders[manifest.PlaceholderEmpty]; emptyFiles > 0 {
+		fmt.Fprintf(messages, "Created %d empty placeholder files\n", emptyFiles)
+	}
+	for _, kind := range []string{manifest.PlaceholderHeader, manifest.PlaceholderSkeleton, manifest.PlaceholderSized} {
+		if placeholders[kind] > 0 {
+			fmt.Fprintf(messages, "Created %d %s placeholder files\n", placeholders[kind], kind)
+		}
+	}
+	if len(values) > 0 {
+		fmt.Fprintf(messages, "Filled in %d variable(s)\n", len(values))
 	}
 
 	return nil
 }
 
-// validateRestoreTemplateName checks if the template name is valid for restoration
+// resolveVariables returns the values of a template's variables: the recorded
+// values of non-sensitive variables, or values read from the environment variables
+// of the same names or prompted for on a terminal, where sensitive values are not
+// echoed. Required variables without a value are an error.
+func resolveVariables(m *manifest.Manifest, recorded map[string]string) (map[string]string, error) {
+	values := make(map[string]string, len(m.Variables))
+	interactive := prompt.IsTerminal(os.Stdin)
+
+	var missing []string
+	for _, v := range m.Variables {
+		value, ok := recorded[v.Name]
+		if !ok || v.Sensitive {
+			value, ok = os.LookupEnv(v.Name)
+		}
+		if !ok && interactive {
+			label := v.Name
+			if v.Description != "" {
+				label += " (" + v.Description + ")"
+			}
+
+			var err error
+			if v.Sensitive {
+				value, err = prompt.ReadSecret(label + ": ")
+			} else {
+				value, err = prompt.ReadLine(label + ": ")
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if value == "" && v.Required {
+			missing = append(missing, v.Name)
+			continue
+		}
+		values[v.Name] = value
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no value for required variable(s) %s; set them as environment variables", strings.Join(missing, ", "))
+	}
+	return values, nil
+}
+
+// recordedVariables returns the values of a template's non-sensitive variables,
+// which are recorded for the project so that update can render the template again
+func recordedVariables(m *manifest.Manifest, values map[string]string) map[string]string {
+	var recorded map[string]string
+	for _, v := range m.Variables {
+		value, ok := values[v.Name]
+		if !ok || v.Sensitive {
+			continue
+		}
+		if recorded == nil {
+			recorded = make(map[string]string)
+		}
+		recorded[v.Name] = value
+	}
+	return recorded
+}
+
+// openContent opens the stored contents of a template file, or of its placeholder,
+// rendering the variables of templated files, and returns them with their size
+func openContent(store *storage.Storage, templateName string, fileEntry manifest.FileEntry, values map[string]string) (io.ReadCloser, int64, error) {
+	content, err := store.OpenFile(templateName, fileEntry.Hash, fileEntry.Compressed)
+	if err != nil || fileEntry.IncludeContents && !fileEntry.Templated {
+		return content, fileEntry.OriginalSize, err
+	}
+	defer content.Close()
+
+	// The size of a placeholder is only known once it is read, and the size of
+	// a templated file once it is rendered
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, 0, err
+	}
+	if fileEntry.Templated {
+		data = manifest.Render(data, values)
+	}
+	return io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+}
+
+// validateRestoreTemplateName checks if the template name is valid for restoration.
+// The name may be omitted when restoring from a bundle or template directory.
 func validateRestoreTemplateName(name string) error {
-	if name == "" {
+	if name == "" && restoreFrom == "" {
 		return fmt.Errorf("template name cannot be empty")
 	}
 	return nil
 }
 
+// openRestoreSource opens the storage holding the template to restore and loads the
+// selected version of its manifest. By default this is the local template store;
+// with --from it is a bundle, a template directory or a template store directory
+// elsewhere, which is read without modifying the local store. The returned cleanup
+// function must be called once restoring is done.
+func openRestoreSource() (*storage.Storage, string, *manifest.Manifest, func(), error) {
+	noCleanup := func() {}
+	name, selector := storage.ParseTemplateRef(restoreTemplateName)
+
+	var store *storage.Storage
+	var err error
+	switch {
+	case restoreFrom == "":
+		store, err = storage.NewStorage("")
+		if err != nil {
+			return nil, "", nil, nil, fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+	case isDirectory(restoreFrom):
+		// A template directory is read through a storage rooted at its parent
+		if _, err := os.Stat(filepath.Join(restoreFrom, storage.ManifestFileName)); err == nil {
+			if name != "" && name != filepath.Base(filepath.Clean(restoreFrom)) {
+				return nil, "", nil, nil, fmt.Errorf("template directory %s does not hold template '%s'", restoreFrom, name)
+			}
+			name = filepath.Base(filepath.Clean(restoreFrom))
+			store, err = storage.NewStorage(filepath.Dir(filepath.Clean(restoreFrom)))
+		} else {
+			if name == "" {
+				return nil, "", nil, nil, fmt.Errorf("--name is required when restoring from a template store directory")
+			}
+			store, err = storage.NewStorage(restoreFrom)
+		}
+		if err != nil {
+			return nil, "", nil, nil, fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+	default:
+		if selector != "" {
+			return nil, "", nil, nil, fmt.Errorf("a bundle holds a single template version; remove '@%s' from --name", selector)
+		}
+		return openBundleSource(name)
+	}
+
+	// Check if template exists and load the manifest of the selected version
+	if !store.TemplateExists(name) {
+		return nil, "", nil, nil, fmt.Errorf("template '%s' does not exist", name)
+	}
+
+	m, err := store.LoadVersion(name, selector)
+	if err != nil {
+		return nil, "", nil, nil, fmt.Errorf("failed to load template manifest: %w", err)
+	}
+
+	return store, name, m, noCleanup, nil
+}
+
+// openBundleSource unpacks the contents of an exported bundle into a temporary
+// storage and returns it together with the bundle's manifest
+func openBundleSource(name string) (*storage.Storage, string, *manifest.Manifest, func(), error) {
+	reader, err := bundle.Open(restoreFrom)
+	if err != nil {
+		return nil, "", nil, nil, err
+	}
+	defer reader.Close()
+
+	m := reader.Manifest
+	if name != "" && name != m.Name {
+		return nil, "", nil, nil, fmt.Errorf("bundle %s holds template '%s', not '%s'", restoreFrom, m.Name, name)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "tmpltr-restore-*")
+	if err != nil {
+		return nil, "", nil, nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+
+	// Blobs are re-hashed as on import, so that a corrupt bundle is not restored
+	store, err := storage.NewStorage(tmpDir)
+	if err == nil {
+		err = store.EnsureTemplateDir(m.Name)
+	}
+	if err == nil {
+		err = importBlobs(reader, m.Name, m, store)
+	}
+	if err != nil {
+		cleanup()
+		return nil, "", nil, nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	return store, m.Name, m, cleanup, nil
+}
+
+// isDirectory reports whether path is an existing directory
+func isDirectory(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// validateRestoreFormat checks if the restore output format is supported
+func validateRestoreFormat(format string) error {
+	switch format {
+	case restoreFormatDir, restoreFormatTar, restoreFormatTarGz:
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format: %s (expected dir, tar or tar.gz)", format)
+	}
+}
+
 // validateOutputDirectory checks if the output directory path is valid
 func validateOutputDirectory(outputDir string) error {
 	if outputDir == "" {
@@ -139,10 +453,14 @@ func validateOutputDirectory(outputDir string) error {
 	return nil
 }
 
-// restoreFile restores a single file from the template storage
-func restoreFile(fileEntry manifest.FileEntry, outputDir string, storage *storage.Storage) error {
+// restoreFile restores a single file from the template storage, filling in the
+// variables of a templated file
+func restoreFile(templateName string, fileEntry manifest.FileEntry, outputDir string, values map[string]string, storage *storage.Storage) error {
 	// Calculate target file path
-	targetPath := filepath.Join(outputDir, fileEntry.OriginalPath)
+	targetPath, err := restoreTarget(outputDir, fileEntry.OriginalPath)
+	if err != nil {
+		return err
+	}
 	
 	// Create parent directories if they don't exist
 	parentDir := filepath.Dir(targetPath)
@@ -150,25 +468,180 @@ func restoreFile(fileEntry manifest.FileEntry, outputDir string, storage *storag
 		return fmt.Errorf("failed to create parent directory for %s: %w", fileEntry.OriginalPath, err)
 	}
 
-	if fileEntry.IncludeContents {
-		// Load file content from storage with decompression if needed
-		content, err := storage.LoadFileWithDecompression(restoreTemplateName, fileEntry.Hash, fileEntry.Compressed)
-		if err != nil {
-			return fmt.Errorf("failed to load file content for %s: %w", fileEntry.OriginalPath, err)
+	file, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileEntry.FileMode())
+	if err != nil {
+		return fmt.Errorf("failed to write file %s: %w", fileEntry.OriginalPath, err)
+	}
+	if err := writeFileContents(file, templateName, fileEntry, values, storage); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", fileEntry.OriginalPath, err)
+	}
+
+	// Apply the recorded mode exactly, regardless of the umask
+	if fileEntry.Mode != 0 {
+		if err := os.Chmod(targetPath, fileEntry.FileMode()); err != nil {
+			return fmt.Errorf("failed to set mode of %s: %w", fileEntry.OriginalPath, err)
 		}
+	}
 
-		// Write content to target file
-		if err := os.WriteFile(targetPath, content, 0644); err != nil {
+	return nil
+}
+
+// restoreTarget returns the path a template file is restored to below dir,
+// refusing paths that would leave it
+func restoreTarget(dir, originalPath string) (string, error) {
+	if err := manifest.ValidatePath(originalPath); err != nil {
+		return "", err
+	}
+
+	target := filepath.Join(dir, originalPath)
+	rel, err := filepath.Rel(dir, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file path leaves the output directory: %s", originalPath)
+	}
+	return target, nil
+}
+
+// writeFileContents writes the contents of a template file to a newly created
+// file: the stored contents or placeholder, streamed from storage, or for a sized
+// placeholder a sparse file of the original size. Files without either stay empty.
+func writeFileContents(file *os.File, templateName string, fileEntry manifest.FileEntry, values map[string]string, storage *storage.Storage) error {
+	if fileEntry.Placeholder == manifest.PlaceholderSized && !fileEntry.IncludeContents {
+		if err := file.Truncate(fileEntry.OriginalSize); err != nil {
+			return fmt.Errorf("failed to size placeholder %s: %w", fileEntry.OriginalPath, err)
+		}
+		return nil
+	}
+	if !fileEntry.StoresContents() {
+		return nil
+	}
+
+	// Stream content from storage, decompressing if needed
+	content, _, err := openContent(storage, templateName, fileEntry, values)
+	if err != nil {
+		return fmt.Errorf("failed to load file content for %s: %w", fileEntry.OriginalPath, err)
+	}
+	defer content.Close()
+
+	if _, err := io.Copy(file, content); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", fileEntry.OriginalPath, err)
+	}
+	return nil
+}
+
+// restoreToArchive writes the files of a template as a tar stream, optionally
+// gzip-compressed, including the project record. Parent directories are written
+// before the files they contain so that the stream extracts with any tar tool.
+// The variables of templated files are filled in with values.
+func restoreToArchive(out io.Writer, compress bool, templateName string, m *manifest.Manifest, values map[string]string, record *project.Record, storage *storage.Storage) error {
+	var gzipWriter *gzip.Writer
+	if compress {
+		gzipWriter = gzip.NewWriter(out)
+		out = gzipWriter
+	}
+	tarWriter := tar.NewWriter(out)
+	modTime := m.VersionTime()
+
+	writtenDirs := make(map[string]bool)
+	writeDirs := func(filePath string) error {
+		var dirs []string
+		for dir := path.Dir(filePath); dir != "." && dir != "/" && !writtenDirs[dir]; dir = path.Dir(dir) {
+			dirs = append(dirs, dir)
+		}
+		for i := len(dirs) - 1; i >= 0; i-- {
+			writtenDirs[dirs[i]] = true
+			err := tarWriter.WriteHeader(&tar.Header{
+				Name:     dirs[i] + "/",
+				Mode:     0755,
+				ModTime:  modTime,
+				Typeflag: tar.TypeDir,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write directory %s: %w", dirs[i], err)
+			}
+		}
+		return nil
+	}
+
+	for _, fileEntry := range m.Files {
+		if err := manifest.ValidatePath(fileEntry.OriginalPath); err != nil {
+			return err
+		}
+		name := filepath.ToSlash(fileEntry.OriginalPath)
+		if err := writeDirs(name); err != nil {
+			return err
+		}
+
+		header := &tar.Header{
+			Name:     name,
+			Mode:     int64(fileEntry.FileMode()),
+			ModTime:  modTime,
+			Typeflag: tar.TypeReg,
+		}
+		var content io.ReadCloser
+		switch {
+		case fileEntry.StoresContents():
+			var err error
+			content, header.Size, err = openContent(storage, templateName, fileEntry, values)
+			if err != nil {
+				return fmt.Errorf("failed to load file content for %s: %w", fileEntry.OriginalPath, err)
+			}
+		case fileEntry.Placeholder == manifest.PlaceholderSized:
+			// Tar streams hold sized placeholders as zeros
+			header.Size = fileEntry.OriginalSize
+			content = io.NopCloser(io.LimitReader(zeroReader{}, fileEntry.OriginalSize))
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			if content != nil {
+				content.Close()
+			}
 			return fmt.Errorf("failed to write file %s: %w", fileEntry.OriginalPath, err)
 		}
-	} else {
-		// Create empty file for ignore-contents mode
-		file, err := os.Create(targetPath)
-		if err != nil {
-			return fmt.Errorf("failed to create empty file %s: %w", fileEntry.OriginalPath, err)
+
+		if content != nil {
+			_, err := io.Copy(tarWriter, content)
+			content.Close()
+			if err != nil {
+				return fmt.Errorf("failed to write file %s: %w", fileEntry.OriginalPath, err)
+			}
 		}
-		file.Close()
 	}
 
+	recordData, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal project record: %w", err)
+	}
+	recordData = append(recordData, '\n')
+	err = tarWriter.WriteHeader(&tar.Header{
+		Name:     project.FileName,
+		Mode:     0644,
+		Size:     int64(len(recordData)),
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	})
+	if err == nil {
+		_, err = tarWriter.Write(recordData)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write project record: %w", err)
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish tar stream: %w", err)
+	}
+	if gzipWriter != nil {
+		return gzipWriter.Close()
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+// zeroReader reads an endless stream of zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
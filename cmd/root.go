@@ -21,11 +21,17 @@ Key features:
   • Hash-based file deduplication
   • Optional content-only or structure-only modes
   • List and manage saved templates
+  • Versioned templates with history
+  • Diffs between templates and template versions
+  • Three-way updates of projects to newer template versions
+  • Export and import of templates as portable archives
 
 Examples:
   tmpltr make ./my-project --name="my-template"
   tmpltr restore --name="my-template" --output="./new-project"
   tmpltr list
+  tmpltr history --name="my-template"
+  tmpltr update ./new-project
   tmpltr delete --name="my-template"`,
 }
 
@@ -45,6 +51,13 @@ func init() {
 	rootCmd.AddCommand(restoreCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(deleteCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(updateCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(checkIgnoreCmd)
+	rootCmd.AddCommand(initIgnoreCmd)
 
 	// Global flags can be added here if needed
 	// rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
@@ -0,0 +1,450 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"tmpltr/internal/hash"
+	"tmpltr/internal/manifest"
+	"tmpltr/internal/project"
+	"tmpltr/internal/storage"
+	"tmpltr/internal/textdiff"
+)
+
+var (
+	updateToVersion string
+	updateDryRun    bool
+)
+
+// updateCmd represents the update command
+var updateCmd = &cobra.Command{
+	Use:   "update <project_directory>",
+	Short: "Apply a newer template version to a restored project",
+	Long: `Bring a project restored from a template up to date with a newer version of
+that template. The template and version the project was restored from are read
+from its .tmpltr.json file.
+
+Changes between the recorded and the new template version are applied as a
+three-way merge onto the project's current files: files the project did not
+touch are replaced, local edits are kept, and where local edits and template
+changes collide, conflict markers are written into the file. Locally edited
+binary files and files too large to merge are kept as they are and reported
+as unmerged. Files holding redacted secrets are rendered with the values of
+their variables before being compared and merged: values recorded in
+.tmpltr.json are reused, and sensitive ones are supplied again as for restore.
+
+Examples:
+  tmpltr update ./my-project
+  tmpltr update ./my-project --to="v3"
+  tmpltr update ./my-project --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: runUpdate,
+}
+
+func init() {
+	updateCmd.Flags().StringVar(&updateToVersion, "to", "", "Template version to update to (default latest)")
+	updateCmd.Flags().BoolVar(&updateDryRun, "dry-run", false, "Show what would change without modifying files")
+
+	// Add completion for directory arguments
+	updateCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	}
+}
+
+// updateResult is the outcome of applying a template change to one project file
+type updateResult string
+
+const (
+	resultAdded     updateResult = "added"
+	resultUpdated   updateResult = "updated"
+	resultRemoved   updateResult = "removed"
+	resultMerged    updateResult = "merged"
+	resultConflict  updateResult = "conflict"
+	resultUnmerged  updateResult = "unmerged"
+	resultKept      updateResult = "kept"
+	resultSkipped   updateResult = "skipped"
+	resultUnchanged updateResult = "unchanged"
+)
+
+// projectUpdater applies the changes between two template versions to a project
+type projectUpdater struct {
+	dir          string
+	templateName string
+	storage      *storage.Storage
+	old, new     *manifest.Manifest
+	dryRun       bool
+	recorded     map[string]string // Values of the variables recorded for the project
+	values       map[string]string // Values of the variables of both versions, nil until needed
+}
+
+// runUpdate executes the update command logic
+func runUpdate(cmd *cobra.Command, args []string) error {
+	projectDir := args[0]
+
+	if err := validateTargetDirectory(projectDir); err != nil {
+		return err
+	}
+
+	record, err := project.Load(projectDir)
+	if err != nil {
+		return err
+	}
+
+	// Initialize storage
+	storage, err := storage.NewStorage("")
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	oldManifest, err := storage.LoadVersion(record.Template, strconv.Itoa(record.Version))
+	if err != nil {
+		return fmt.Errorf("failed to load recorded template version: %w", err)
+	}
+
+	newManifest, err := storage.LoadVersion(record.Template, updateToVersion)
+	if err != nil {
+		return fmt.Errorf("failed to load template manifest: %w", err)
+	}
+
+	if newManifest.Version == oldManifest.Version {
+		fmt.Printf("Project is already at version %d of template '%s'\n", newManifest.Version, record.Template)
+		return nil
+	}
+
+	updater := &projectUpdater{
+		dir:          projectDir,
+		templateName: record.Template,
+		storage:      storage,
+		old:          oldManifest,
+		new:          newManifest,
+		dryRun:       updateDryRun,
+		recorded:     record.Variables,
+	}
+
+	fmt.Printf("Updating '%s' from version %d to version %d of template '%s'\n",
+		projectDir, oldManifest.Version, newManifest.Version, record.Template)
+
+	counts := make(map[updateResult]int)
+	for _, change := range manifest.Compare(oldManifest, newManifest) {
+		result, detail, err := updater.apply(change)
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %w", change.Path, err)
+		}
+		counts[result]++
+		if result == resultUnchanged {
+			continue
+		}
+		if detail != "" {
+			fmt.Printf("  %-9s %s (%s)\n", result, change.Path, detail)
+		} else {
+			fmt.Printf("  %-9s %s\n", result, change.Path)
+		}
+	}
+
+	fmt.Printf("\n%d added, %d updated, %d removed, %d merged, %d conflicts, %d unmerged, %d kept, %d skipped\n",
+		counts[resultAdded], counts[resultUpdated], counts[resultRemoved], counts[resultMerged],
+		counts[resultConflict], counts[resultUnmerged], counts[resultKept], counts[resultSkipped])
+
+	if updateDryRun {
+		fmt.Println("Dry run: no files were modified")
+		return nil
+	}
+
+	record.Version = newManifest.Version
+	record.RestoredAt = time.Now().UTC()
+	if updater.values != nil {
+		record.Variables = recordedVariables(newManifest, updater.values)
+	}
+	if err := project.Save(projectDir, record); err != nil {
+		return err
+	}
+
+	if counts[resultConflict] > 0 {
+		fmt.Printf("Resolve the conflict markers in %d file(s) before using the project\n", counts[resultConflict])
+	}
+	if counts[resultUnmerged] > 0 {
+		fmt.Printf("Apply the template changes to %d unmerged file(s) by hand\n", counts[resultUnmerged])
+	}
+
+	return nil
+}
+
+// apply applies a single template change to the project and describes the outcome
+func (u *projectUpdater) apply(change manifest.Change) (updateResult, string, error) {
+	path, err := restoreTarget(u.dir, change.Path)
+	if err != nil {
+		return "", "", err
+	}
+	current, exists, err := u.currentHash(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch change.Kind {
+	case manifest.ChangeAdded:
+		if !exists {
+			return resultAdded, "", u.writeEntry(path, change.New)
+		}
+		if !change.New.IncludeContents {
+			return resultUnchanged, "", nil
+		}
+		same, err := u.restoredUnchanged(change.New, current)
+		if err != nil || same {
+			return resultUnchanged, "", err
+		}
+		// Both the project and the template added the file; merge from an empty base
+		return u.merge(path, nil, change.New)
+
+	case manifest.ChangeRemoved:
+		if !exists {
+			return resultUnchanged, "", nil
+		}
+		untouched, err := u.restoredUnchanged(change.Old, current)
+		if err != nil {
+			return "", "", err
+		}
+		if !untouched {
+			return resultKept, "modified locally", nil
+		}
+		if u.dryRun {
+			return resultRemoved, "", nil
+		}
+		if err := os.Remove(path); err != nil {
+			return "", "", fmt.Errorf("failed to remove file: %w", err)
+		}
+		return resultRemoved, "", nil
+
+	default:
+		if !exists {
+			return resultSkipped, "deleted locally", nil
+		}
+		if !change.New.IncludeContents {
+			return resultUnchanged, "", nil
+		}
+		same, err := u.restoredUnchanged(change.New, current)
+		if err != nil || same {
+			return resultUnchanged, "", err
+		}
+		untouched, err := u.restoredUnchanged(change.Old, current)
+		if err != nil {
+			return "", "", err
+		}
+		if untouched {
+			return resultUpdated, "", u.writeEntry(path, change.New)
+		}
+		if !change.Old.IncludeContents {
+			// Only a placeholder was restored, which the project replaced with its
+			// own contents; merge from an empty base as for files both added
+			return u.merge(path, nil, change.New)
+		}
+		return u.merge(path, change.Old, change.New)
+	}
+}
+
+// restoredUnchanged reports whether a project file with the given content hash
+// still holds what the template entry was restored as: its contents, or for a
+// structure-only entry its placeholder, rendered with the project's variables
+// if they hold redacted secrets
+func (u *projectUpdater) restoredUnchanged(entry *manifest.FileEntry, current string) (bool, error) {
+	switch {
+	case entry.Templated:
+		content, err := u.readStored(entry)
+		if err != nil || content == nil {
+			return false, err
+		}
+		return current == hash.HashBytes(content), nil
+	case entry.StoresContents():
+		return current == entry.Hash, nil
+	case entry.Placeholder == manifest.PlaceholderSized:
+		zeros, err := hash.HashReader(io.LimitReader(zeroReader{}, entry.OriginalSize))
+		return current == zeros, err
+	default:
+		return current == hash.HashBytes(nil), nil
+	}
+}
+
+// currentHash returns the content hash of a project file and whether it exists
+func (u *projectUpdater) currentHash(path string) (string, bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to access file: %w", err)
+	}
+
+	fileHash, err := hash.HashFile(path)
+	if err != nil {
+		return "", false, err
+	}
+	return fileHash, true, nil
+}
+
+// writeEntry replaces a project file with the contents of a template entry
+func (u *projectUpdater) writeEntry(path string, entry *manifest.FileEntry) error {
+	if u.dryRun {
+		return nil
+	}
+
+	if entry.Templated {
+		if _, err := u.variables(); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, entry.FileMode())
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := writeFileContents(file, u.templateName, *entry, u.values, u.storage); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	// Apply the recorded mode exactly, also to files that already existed
+	if entry.Mode != 0 {
+		if err := os.Chmod(path, entry.FileMode()); err != nil {
+			return fmt.Errorf("failed to set file mode: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// merge three-way merges the template change from base to theirs into the project
+// file. A nil base stands for a file that did not exist in the old version.
+func (u *projectUpdater) merge(path string, base, theirs *manifest.FileEntry) (updateResult, string, error) {
+	ours, err := readLimited(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	var baseContent, theirsContent []byte
+	if base != nil {
+		if baseContent, err = u.readEntry(base); err != nil {
+			return "", "", err
+		}
+	}
+	if theirsContent, err = u.readEntry(theirs); err != nil {
+		return "", "", err
+	}
+
+	if ours == nil || baseContent == nil && base != nil || theirsContent == nil ||
+		textdiff.IsBinary(ours) || textdiff.IsBinary(baseContent) || textdiff.IsBinary(theirsContent) {
+		return resultUnmerged, "binary or too large to merge, kept local version", nil
+	}
+
+	merged, conflicts := textdiff.Merge(string(baseContent), string(ours), string(theirsContent),
+		"local", fmt.Sprintf("%s@%d", u.templateName, u.new.Version))
+
+	if !u.dryRun {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to access file: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(merged), info.Mode().Perm()); err != nil {
+			return "", "", fmt.Errorf("failed to write merged file: %w", err)
+		}
+	}
+
+	if conflicts > 0 {
+		return resultConflict, fmt.Sprintf("%d conflicting region(s)", conflicts), nil
+	}
+	return resultMerged, "", nil
+}
+
+// readEntry reads the contents of a template entry for merging, returning nil
+// if the file is too large to merge in memory
+func (u *projectUpdater) readEntry(entry *manifest.FileEntry) ([]byte, error) {
+	if !entry.IncludeContents {
+		return []byte{}, nil
+	}
+	return u.readStored(entry)
+}
+
+// readStored reads the stored contents of a template entry, or of its placeholder,
+// rendering the variables of templated files. It returns nil if they are too large
+// to handle in memory.
+func (u *projectUpdater) readStored(entry *manifest.FileEntry) ([]byte, error) {
+	if entry.IncludeContents && entry.OriginalSize > maxPatchSize {
+		return nil, nil
+	}
+
+	content, err := u.storage.OpenFile(u.templateName, entry.Hash, entry.Compressed)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(io.LimitReader(content, maxPatchSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template file: %w", err)
+	}
+	if len(data) > maxPatchSize {
+		return nil, nil
+	}
+
+	if entry.Templated {
+		values, err := u.variables()
+		if err != nil {
+			return nil, err
+		}
+		data = manifest.Render(data, values)
+	}
+	return data, nil
+}
+
+// variables returns the values of the variables of the old and new template
+// versions, resolving them the first time they are needed: the values recorded
+// for the project are reused, and the others are supplied as for restore
+func (u *projectUpdater) variables() (map[string]string, error) {
+	if u.values != nil {
+		return u.values, nil
+	}
+
+	combined := &manifest.Manifest{}
+	for _, v := range u.old.Variables {
+		combined.AddVariable(v)
+	}
+	for _, v := range u.new.Variables {
+		combined.AddVariable(v)
+	}
+
+	values, err := resolveVariables(combined, u.recorded)
+	if err != nil {
+		return nil, err
+	}
+	u.values = values
+	return values, nil
+}
+
+// readLimited reads a project file for merging, returning nil if it is too large
+// to merge in memory
+func readLimited(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access file: %w", err)
+	}
+	if info.Size() > maxPatchSize {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,147 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// gzipMagic is the header every gzip stream starts with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// zipMagic is the header of a zip archive's first local file
+var zipMagic = []byte("PK\x03\x04")
+
+// Entry describes a regular file in an archive
+type Entry struct {
+	Name string      // Cleaned, slash-separated path of the file inside the archive
+	Mode fs.FileMode // Permission bits of the file
+	Size int64       // Size of the file contents
+}
+
+// WalkFunc is called for every regular file in an archive. The contents are
+// only valid until the function returns.
+type WalkFunc func(entry Entry, r io.Reader) error
+
+// WalkFile calls fn for every regular file in a tar, gzip-compressed tar or zip
+// archive. The format is detected from the contents rather than the file name.
+func WalkFile(filePath string, fn WalkFunc) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(zipMagic))
+	n, err := io.ReadFull(file, magic)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	if bytes.Equal(magic[:n], zipMagic) {
+		info, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		return walkZip(file, info.Size(), fn)
+	}
+
+	return WalkStream(file, fn)
+}
+
+// WalkStream calls fn for every regular file in a tar stream, which may be
+// gzip-compressed. Zip archives cannot be read from a stream.
+func WalkStream(r io.Reader, fn WalkFunc) error {
+	buffered := bufio.NewReader(r)
+	magic, _ := buffered.Peek(len(zipMagic))
+	if bytes.HasPrefix(magic, zipMagic) {
+		return fmt.Errorf("zip archives cannot be read from a stream, pass the archive file instead")
+	}
+
+	var input io.Reader = buffered
+	if bytes.HasPrefix(magic, gzipMagic) {
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		defer gzipReader.Close()
+		input = gzipReader
+	}
+
+	tarReader := tar.NewReader(input)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name, err := cleanName(header.Name)
+		if err != nil {
+			return err
+		}
+
+		entry := Entry{Name: name, Mode: fs.FileMode(header.Mode).Perm(), Size: header.Size}
+		if err := fn(entry, tarReader); err != nil {
+			return err
+		}
+	}
+}
+
+// walkZip calls fn for every regular file in a zip archive
+func walkZip(r io.ReaderAt, size int64, fn WalkFunc) error {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	for _, file := range zipReader.File {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+
+		name, err := cleanName(file.Name)
+		if err != nil {
+			return err
+		}
+
+		contents, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s from archive: %w", name, err)
+		}
+		entry := Entry{Name: name, Mode: file.Mode().Perm(), Size: int64(file.UncompressedSize64)}
+		err = fn(entry, contents)
+		contents.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cleanName normalizes the path of an archive entry, rejecting paths that would
+// point outside of the archive root
+func cleanName(name string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("archive entry has an unsafe path: %s", name)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,344 @@
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"tmpltr/internal/hash"
+	"tmpltr/internal/manifest"
+)
+
+const (
+	// HeaderFileName is the first entry of every bundle and identifies its format
+	HeaderFileName = "tmpltr-bundle.json"
+
+	// ManifestFileName is the entry holding the template manifest
+	ManifestFileName = "manifest.json"
+
+	// FilesDir is the directory holding the content blobs, named by hash
+	FilesDir = "files"
+
+	// FormatName identifies tmpltr bundles in the header
+	FormatName = "tmpltr-bundle"
+
+	// FormatVersion is the bundle format version written by this version of tmpltr
+	FormatVersion = 1
+)
+
+// Format is an archive format a bundle can be stored in
+type Format string
+
+const (
+	FormatTar   Format = "tar"
+	FormatTarGz Format = "tar.gz"
+	FormatZip   Format = "zip"
+)
+
+// Header describes a bundle
+type Header struct {
+	Format        string    `json:"format"`         // Always FormatName
+	FormatVersion int       `json:"format_version"` // Version of the bundle layout
+	Template      string    `json:"template"`       // Name of the exported template
+	Version       int       `json:"version"`        // Exported version of the template
+	ExportedAt    time.Time `json:"exported_at"`    // Export timestamp
+}
+
+// DetectFormat determines the archive format of a bundle from its file name
+func DetectFormat(filePath string) (Format, error) {
+	name := strings.ToLower(filePath)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return FormatTarGz, nil
+	case strings.HasSuffix(name, ".tar"):
+		return FormatTar, nil
+	case strings.HasSuffix(name, ".zip"):
+		return FormatZip, nil
+	default:
+		return "", fmt.Errorf("unknown bundle format for %s (expected .tar.gz, .tgz, .tar or .zip)", filePath)
+	}
+}
+
+// ParseFormat parses an archive format name as accepted on the command line
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case FormatTar, FormatTarGz, FormatZip:
+		return Format(name), nil
+	case "tgz":
+		return FormatTarGz, nil
+	default:
+		return "", fmt.Errorf("unsupported bundle format: %s (expected tar, tar.gz or zip)", name)
+	}
+}
+
+// BlobPath returns the path of a content blob inside a bundle
+func BlobPath(hash string) string {
+	return path.Join(FilesDir, hash)
+}
+
+// Writer writes a bundle: the header, the manifest and the content blobs, in that order
+type Writer struct {
+	tarWriter  *tar.Writer
+	gzipWriter *gzip.Writer
+	zipWriter  *zip.Writer
+}
+
+// NewWriter starts writing a bundle of the given manifest to w
+func NewWriter(w io.Writer, format Format, m *manifest.Manifest) (*Writer, error) {
+	bw := &Writer{}
+	switch format {
+	case FormatTar:
+		bw.tarWriter = tar.NewWriter(w)
+	case FormatTarGz:
+		bw.gzipWriter = gzip.NewWriter(w)
+		bw.tarWriter = tar.NewWriter(bw.gzipWriter)
+	case FormatZip:
+		bw.zipWriter = zip.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported bundle format: %s", format)
+	}
+
+	header, err := json.MarshalIndent(Header{
+		Format:        FormatName,
+		FormatVersion: FormatVersion,
+		Template:      m.Name,
+		Version:       m.Version,
+		ExportedAt:    time.Now().UTC(),
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle header: %w", err)
+	}
+
+	manifestData, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	if err := bw.AddFile(HeaderFileName, int64(len(header)), strings.NewReader(string(header))); err != nil {
+		return nil, err
+	}
+	if err := bw.AddFile(ManifestFileName, int64(len(manifestData)), strings.NewReader(string(manifestData))); err != nil {
+		return nil, err
+	}
+
+	return bw, nil
+}
+
+// AddFile adds an entry of the given size to the bundle
+func (bw *Writer) AddFile(name string, size int64, r io.Reader) error {
+	if bw.zipWriter != nil {
+		w, err := bw.zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add %s to bundle: %w", name, err)
+		}
+		if _, err := io.Copy(w, r); err != nil {
+			return fmt.Errorf("failed to add %s to bundle: %w", name, err)
+		}
+		return nil
+	}
+
+	err := bw.tarWriter.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s to bundle: %w", name, err)
+	}
+	if _, err := io.Copy(bw.tarWriter, r); err != nil {
+		return fmt.Errorf("failed to add %s to bundle: %w", name, err)
+	}
+	return nil
+}
+
+// Close finishes the bundle. It does not close the underlying writer.
+func (bw *Writer) Close() error {
+	if bw.zipWriter != nil {
+		return bw.zipWriter.Close()
+	}
+	if err := bw.tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish bundle: %w", err)
+	}
+	if bw.gzipWriter != nil {
+		return bw.gzipWriter.Close()
+	}
+	return nil
+}
+
+// Reader reads a bundle sequentially. The header and manifest are read by Open;
+// the content blobs are then read one at a time with NextBlob.
+type Reader struct {
+	Header   Header
+	Manifest *manifest.Manifest
+
+	file      *os.File
+	gzip      *gzip.Reader
+	tarReader *tar.Reader
+	zipFiles  []*zip.File
+	zipIndex  int
+	current   io.ReadCloser
+}
+
+// Open opens a bundle file and reads its header and manifest
+func Open(filePath string) (*Reader, error) {
+	format, err := DetectFormat(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+
+	br := &Reader{file: file}
+	if err := br.init(format); err != nil {
+		br.Close()
+		return nil, err
+	}
+
+	return br, nil
+}
+
+// init prepares the archive reader and reads the header and manifest
+func (br *Reader) init(format Format) error {
+	switch format {
+	case FormatTar:
+		br.tarReader = tar.NewReader(br.file)
+	case FormatTarGz:
+		gzipReader, err := gzip.NewReader(br.file)
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+		br.gzip = gzipReader
+		br.tarReader = tar.NewReader(gzipReader)
+	case FormatZip:
+		info, err := br.file.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+		zipReader, err := zip.NewReader(br.file, info.Size())
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+		br.zipFiles = zipReader.File
+	}
+
+	if err := br.readJSON(HeaderFileName, &br.Header); err != nil {
+		return err
+	}
+	if br.Header.Format != FormatName {
+		return fmt.Errorf("not a tmpltr bundle")
+	}
+	if br.Header.FormatVersion < 1 || br.Header.FormatVersion > FormatVersion {
+		return fmt.Errorf("unsupported bundle format version %d (this tmpltr supports up to %d)",
+			br.Header.FormatVersion, FormatVersion)
+	}
+
+	br.Manifest = &manifest.Manifest{}
+	if err := br.readJSON(ManifestFileName, br.Manifest); err != nil {
+		return err
+	}
+	if err := manifest.ValidateManifest(br.Manifest); err != nil {
+		return fmt.Errorf("invalid manifest in bundle: %w", err)
+	}
+
+	return nil
+}
+
+// readJSON reads the next entry, which must have the given name, as JSON into v
+func (br *Reader) readJSON(name string, v any) error {
+	entryName, r, err := br.next()
+	if err == io.EOF || err == nil && entryName != name {
+		return fmt.Errorf("invalid bundle: expected %s", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("invalid bundle: failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// NextBlob returns the hash and stored contents of the next blob in the bundle,
+// or io.EOF when there are no more blobs. The contents are only valid until the
+// next call.
+func (br *Reader) NextBlob() (string, io.Reader, error) {
+	name, r, err := br.next()
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, blobHash := path.Split(name)
+	if dir != FilesDir+"/" || !hash.ValidateHash(blobHash) {
+		return "", nil, fmt.Errorf("invalid bundle: unexpected entry %s", name)
+	}
+
+	return blobHash, r, nil
+}
+
+// next returns the name and contents of the next regular file entry
+func (br *Reader) next() (string, io.Reader, error) {
+	if br.current != nil {
+		br.current.Close()
+		br.current = nil
+	}
+
+	if br.tarReader != nil {
+		for {
+			header, err := br.tarReader.Next()
+			if err != nil {
+				if err == io.EOF {
+					return "", nil, io.EOF
+				}
+				return "", nil, fmt.Errorf("failed to read bundle: %w", err)
+			}
+			if header.Typeflag == tar.TypeReg {
+				return header.Name, br.tarReader, nil
+			}
+		}
+	}
+
+	for br.zipIndex < len(br.zipFiles) {
+		file := br.zipFiles[br.zipIndex]
+		br.zipIndex++
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		r, err := file.Open()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		br.current = r
+		return file.Name, r, nil
+	}
+
+	return "", nil, io.EOF
+}
+
+// Close closes the bundle
+func (br *Reader) Close() error {
+	if br.current != nil {
+		br.current.Close()
+	}
+	if br.gzip != nil {
+		br.gzip.Close()
+	}
+	return br.file.Close()
+}
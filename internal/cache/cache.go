@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// FileName is the name of the hash cache file
+	FileName = "hashes.json"
+
+	// formatVersion is bumped whenever the cache layout changes; caches written
+	// with another version are discarded instead of being misinterpreted
+	formatVersion = 1
+
+	// racyWindow is how recently a file may have been modified for its entry to
+	// still be trusted. Files modified within this window of being hashed could be
+	// modified again without their modification time changing, so they are not cached.
+	racyWindow = 2 * time.Second
+)
+
+// Entry is the cached information about a file on disk
+type Entry struct {
+	Size       int64  `json:"size"`        // File size in bytes when it was hashed
+	ModTime    int64  `json:"mod_time"`    // Modification time in Unix nanoseconds when it was hashed
+	Inode      uint64 `json:"inode"`       // Inode number when it was hashed (0 where unsupported)
+	Hash       string `json:"hash"`        // SHA256 hash of the file contents
+	Template   string `json:"template"`    // Template whose files/ store holds the blob described below
+	Compressed bool   `json:"compressed"`  // Whether the blob is stored compressed
+	StoredSize int64  `json:"stored_size"` // Size of the stored blob in bytes
+
+	// SecretsClean records that the contents were scanned for secrets and none
+	// were found, so that an unchanged file is not scanned again
+	SecretsClean bool `json:"secrets_clean,omitempty"`
+}
+
+// cacheFile is the on-disk representation of the cache
+type cacheFile struct {
+	Version int              `json:"version"`
+	Entries map[string]Entry `json:"entries"`
+}
+
+// Cache maps files on disk, identified by absolute path, size, modification time
+// and inode, to the hash of their contents and the blob they were stored as.
+// It is safe for concurrent use.
+type Cache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]Entry
+	seen    map[string]bool
+	dirty   bool
+}
+
+// Load reads the cache from the given path. A missing, unreadable or outdated
+// cache file results in an empty cache rather than an error, since the cache
+// can always be rebuilt by hashing files again.
+func Load(path string) *Cache {
+	c := &Cache{
+		path:    path,
+		entries: make(map[string]Entry),
+		seen:    make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != formatVersion {
+		c.dirty = true
+		return c
+	}
+
+	if file.Entries != nil {
+		c.entries = file.Entries
+	}
+	return c
+}
+
+// Lookup returns the cached entry for the file at absPath if the file has not
+// changed since it was cached
+func (c *Cache) Lookup(absPath string, info os.FileInfo) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seen[absPath] = true
+
+	entry, ok := c.entries[absPath]
+	if !ok {
+		return Entry{}, false
+	}
+
+	if entry.Size != info.Size() || entry.ModTime != info.ModTime().UnixNano() || entry.Inode != inode(info) {
+		delete(c.entries, absPath)
+		c.dirty = true
+		return Entry{}, false
+	}
+
+	return entry, true
+}
+
+// Store records the hash and blob of the file at absPath, described by the
+// file info taken before its contents were read
+func (c *Cache) Store(absPath string, info os.FileInfo, entry Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seen[absPath] = true
+
+	// A file modified this recently may change again within the resolution of
+	// its modification time, so caching it could hide that change
+	if time.Since(info.ModTime()) < racyWindow {
+		if _, ok := c.entries[absPath]; ok {
+			delete(c.entries, absPath)
+			c.dirty = true
+		}
+		return
+	}
+
+	entry.Size = info.Size()
+	entry.ModTime = info.ModTime().UnixNano()
+	entry.Inode = inode(info)
+	c.entries[absPath] = entry
+	c.dirty = true
+}
+
+// PruneUnder removes the entries of files below absRoot that were neither
+// looked up nor stored since the cache was loaded, e.g. files that were deleted
+// or are now ignored
+func (c *Cache) PruneUnder(absRoot string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix := strings.TrimSuffix(absRoot, string(filepath.Separator)) + string(filepath.Separator)
+	for path := range c.entries {
+		if strings.HasPrefix(path, prefix) && !c.seen[path] {
+			delete(c.entries, path)
+			c.dirty = true
+		}
+	}
+}
+
+// Save writes the cache back to disk if it changed. The file is replaced
+// atomically so that concurrent runs never observe a partially written cache.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(cacheFile{Version: formatVersion, Entries: c.entries})
+	if err != nil {
+		return fmt.Errorf("failed to marshal hash cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(c.path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary cache file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write hash cache: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write hash cache: %w", err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), c.path); err != nil {
+		return fmt.Errorf("failed to save hash cache: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
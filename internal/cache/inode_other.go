@@ -0,0 +1,11 @@
+//go:build !unix
+
+package cache
+
+import "os"
+
+// inode returns 0 on platforms that do not expose inode numbers, leaving
+// path, size and modification time to identify the file
+func inode(info os.FileInfo) uint64 {
+	return 0
+}
@@ -52,10 +52,60 @@ func DecompressData(compressedData []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// CompressStream compresses everything read from src into dst using gzip compression
+// and returns the number of uncompressed bytes consumed
+func CompressStream(dst io.Writer, src io.Reader) (int64, error) {
+	gzipWriter := gzip.NewWriter(dst)
+
+	n, err := io.Copy(gzipWriter, src)
+	if err != nil {
+		gzipWriter.Close()
+		return n, fmt.Errorf("failed to write data to gzip writer: %w", err)
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return n, fmt.Errorf("failed to close gzip writer: %w", err)
+	}
+
+	return n, nil
+}
+
+// DecompressStream decompresses gzip data read from src into dst
+// and returns the number of decompressed bytes written
+func DecompressStream(dst io.Writer, src io.Reader) (int64, error) {
+	gzipReader, err := NewDecompressReader(src)
+	if err != nil {
+		return 0, err
+	}
+	defer gzipReader.Close()
+
+	n, err := io.Copy(dst, gzipReader)
+	if err != nil {
+		return n, fmt.Errorf("failed to decompress data: %w", err)
+	}
+
+	return n, nil
+}
+
+// NewDecompressReader returns a reader that decompresses gzip data read from r
+func NewDecompressReader(r io.Reader) (io.ReadCloser, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	return gzipReader, nil
+}
+
 // ShouldCompress determines if a file should be compressed based on size and type
 func ShouldCompress(data []byte, filePath string) bool {
+	return ShouldCompressFile(int64(len(data)), filePath)
+}
+
+// ShouldCompressFile determines if a file should be compressed based on its size and type
+// without requiring its contents, so callers can decide before streaming the file
+func ShouldCompressFile(size int64, filePath string) bool {
 	// Don't compress very small files (less than 100 bytes)
-	if len(data) < 100 {
+	if size < 100 {
 		return false
 	}
 
@@ -99,7 +149,7 @@ func ShouldCompress(data []byte, filePath string) bool {
 	}
 
 	// For unknown file types, compress if file is large enough
-	return len(data) >= 100
+	return size >= 100
 }
 
 // GetCompressionRatio calculates the compression ratio
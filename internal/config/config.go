@@ -0,0 +1,34 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// FileName is the name of the configuration file in the tmpltr directory
+const FileName = "config.json"
+
+// Config holds the user's defaults for command-line options
+type Config struct {
+	GitIgnore bool `json:"gitignore"` // Honor .gitignore files when scanning directories
+}
+
+// Load reads the configuration file at path. A missing file yields the defaults.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
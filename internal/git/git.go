@@ -0,0 +1,195 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tmpltr/internal/archive"
+)
+
+// Git file modes of the tree entries that are captured
+const (
+	modeFile       = "100644"
+	modeExecutable = "100755"
+)
+
+// RepositoryPath returns the local path of a repository given as a path or a
+// file:// URL. Remote repositories are not supported.
+func RepositoryPath(repo string) (string, error) {
+	if !strings.Contains(repo, "://") {
+		return filepath.Abs(repo)
+	}
+
+	u, err := url.Parse(repo)
+	if err != nil {
+		return "", fmt.Errorf("invalid repository URL: %w", err)
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported repository URL scheme %q (only local paths and file:// URLs are supported)", u.Scheme)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
+
+// ResolveCommit returns the full SHA of the commit a ref points to
+func ResolveCommit(repoPath, ref string) (string, error) {
+	out, err := run(repoPath, "rev-parse", "--verify", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// treeFile is a regular file of a commit's tree
+type treeFile struct {
+	path   string
+	object string
+	mode   fs.FileMode
+}
+
+// Walk calls fn for every tracked regular file of a commit, in path order.
+// Symbolic links and submodules are skipped. Unlike git archive, files marked
+// export-ignore are included, so exactly the tracked files are walked.
+func Walk(repoPath, commit string, fn archive.WalkFunc) error {
+	files, err := listTree(repoPath, commit)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	cmd := exec.Command("git", "-C", repoPath, "cat-file", "--batch")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to run git: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to run git: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run git: %w", err)
+	}
+
+	// Request all objects up front while reading them back in order
+	go func() {
+		w := bufio.NewWriter(stdin)
+		for _, file := range files {
+			fmt.Fprintln(w, file.object)
+		}
+		w.Flush()
+		stdin.Close()
+	}()
+
+	walkErr := readBatch(bufio.NewReader(stdout), files, fn)
+	if walkErr != nil {
+		// Stop git early; its exit status is irrelevant after a failure
+		cmd.Process.Kill()
+		cmd.Wait()
+		return walkErr
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("git cat-file failed: %s", gitError(err, &stderr))
+	}
+	return nil
+}
+
+// listTree lists the regular files of a commit's tree
+func listTree(repoPath, commit string) ([]treeFile, error) {
+	out, err := run(repoPath, "ls-tree", "-r", "-z", "--full-tree", commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of commit %s: %w", commit, err)
+	}
+
+	var files []treeFile
+	for _, line := range strings.Split(string(out), "\x00") {
+		if line == "" {
+			continue
+		}
+
+		// Each line is "<mode> <type> <object>\t<path>"
+		meta, path, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected git ls-tree output: %q", line)
+		}
+
+		var mode fs.FileMode
+		switch fields[0] {
+		case modeFile:
+			mode = 0644
+		case modeExecutable:
+			mode = 0755
+		default:
+			continue
+		}
+		files = append(files, treeFile{path: path, object: fields[2], mode: mode})
+	}
+
+	return files, nil
+}
+
+// readBatch reads the objects of the files from git cat-file --batch output
+func readBatch(r *bufio.Reader, files []treeFile, fn archive.WalkFunc) error {
+	for _, file := range files {
+		// Each object is "<object> <type> <size>\n<contents>\n"
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read %s from git: %w", file.path, err)
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 || fields[1] != "blob" {
+			return fmt.Errorf("failed to read %s from git: unexpected object %q", file.path, strings.TrimSpace(header))
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to read %s from git: invalid size %q", file.path, fields[2])
+		}
+
+		contents := io.LimitReader(r, size)
+		if err := fn(archive.Entry{Name: file.path, Mode: file.mode, Size: size}, contents); err != nil {
+			return err
+		}
+
+		// Skip whatever the callback did not read, and the terminating newline
+		if _, err := io.Copy(io.Discard, contents); err != nil {
+			return fmt.Errorf("failed to read %s from git: %w", file.path, err)
+		}
+		if _, err := r.Discard(1); err != nil {
+			return fmt.Errorf("failed to read %s from git: %w", file.path, err)
+		}
+	}
+	return nil
+}
+
+// run runs a git command in a repository and returns its output
+func run(repoPath string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", repoPath}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s", gitError(err, &stderr))
+	}
+	return out, nil
+}
+
+// gitError describes a failed git command, preferring git's own message
+func gitError(err error, stderr *bytes.Buffer) string {
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return msg
+	}
+	return err.Error()
+}
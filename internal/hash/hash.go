@@ -3,6 +3,7 @@ package hash
 import (
 	"crypto/sha256"
 	"fmt"
+	stdhash "hash"
 	"io"
 	"os"
 )
@@ -23,6 +24,27 @@ func HashFile(filePath string) (string, error) {
 	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
 }
 
+// HashReader calculates the SHA256 hash of everything read from r
+func HashReader(r io.Reader) (string, error) {
+	hasher := NewHasher()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return "", fmt.Errorf("failed to read content for hashing: %w", err)
+	}
+
+	return HexSum(hasher), nil
+}
+
+// NewHasher returns a SHA256 hasher for computing content hashes incrementally,
+// e.g. while the content is being streamed elsewhere
+func NewHasher() stdhash.Hash {
+	return sha256.New()
+}
+
+// HexSum returns the hex-encoded digest of a hasher created by NewHasher
+func HexSum(hasher stdhash.Hash) string {
+	return fmt.Sprintf("%x", hasher.Sum(nil))
+}
+
 // HashBytes calculates the SHA256 hash of a byte slice
 func HashBytes(data []byte) string {
 	hasher := sha256.New()
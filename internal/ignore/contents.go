@@ -0,0 +1,40 @@
+package ignore
+
+import (
+	"path/filepath"
+)
+
+// contentsDirective starts a .tmpltrignore line listing comma-separated patterns
+// of files captured as structure only
+const contentsDirective = "contents-ignore:"
+
+// AddContentsPatterns adds patterns of files captured as structure only, naming
+// where they came from in source
+func (ir *IgnoreRules) AddContentsPatterns(patterns []string, source string) {
+	for _, pattern := range patterns {
+		if p, ok := ParsePattern(pattern, ""); ok {
+			p.source = source
+			ir.contents = append(ir.contents, p)
+		}
+	}
+}
+
+// ExplainContents decides whether a file is captured as structure only and
+// returns the deciding pattern, or nil if no pattern matched. The extra patterns
+// take precedence over the contents-ignore directives of .tmpltrignore files,
+// of which deeper ones take precedence, and the last match wins.
+func (ir *IgnoreRules) ExplainContents(filePath string) (bool, *Pattern, error) {
+	relPath, err := filepath.Rel(ir.rootDir, filePath)
+	if err != nil || relPath == "." {
+		return false, nil, nil
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	p := lastMatch(ir.contents, relPath, false)
+	if p == nil && ir.files != nil {
+		if p, err = ir.files.MatchContents(relPath); err != nil {
+			return false, nil, err
+		}
+	}
+	return p != nil && !p.negate, p, nil
+}
@@ -0,0 +1,292 @@
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// GitIgnoreFileName is the name of git's per-directory ignore files
+const GitIgnoreFileName = ".gitignore"
+
+// DirRules holds the patterns of ignore files with a given name, loaded lazily
+// from every directory a path passes through. Patterns of deeper directories
+// take precedence, and within a file the last matching pattern wins.
+//
+// The .tmpltrignore files can also hold size and type directives, which apply
+// to the files below the directory of the file, and contents-ignore directives
+// selecting the files captured as structure only.
+type DirRules struct {
+	topDir     string              // Directory the files are loaded from, "" if they are only set explicitly
+	fileName   string              // Name of the per-directory ignore files
+	directives bool                // Whether the files can hold directives
+	base       []Pattern           // Patterns applying before any per-directory file
+	dirs       map[string]ruleFile // Loaded files keyed by slash-separated directory
+}
+
+// ruleFile holds the rules of an ignore file
+type ruleFile struct {
+	patterns   []Pattern
+	predicates []Predicate // Size and type directives
+	contents   []Pattern   // Patterns of contents-ignore directives
+}
+
+// NewDirRules creates rules loading the ignore files named fileName below topDir
+func NewDirRules(topDir, fileName string) *DirRules {
+	return &DirRules{
+		topDir:     topDir,
+		fileName:   fileName,
+		directives: fileName == IgnoreFileName,
+		dirs:       make(map[string]ruleFile),
+	}
+}
+
+// SetPatterns reads the ignore file of a slash-separated directory from r,
+// replacing any patterns loaded for it
+func (dr *DirRules) SetPatterns(dir string, r io.Reader) error {
+	rules, err := readRules(r, dir, path.Join(dir, dr.fileName), dr.directives)
+	if err != nil {
+		return err
+	}
+	dr.dirs[dir] = rules
+	return nil
+}
+
+// Match evaluates the rules for a slash-separated path relative to topDir and
+// returns the pattern deciding whether it is ignored, or nil if none matched.
+// Only the patterns of the path itself are considered; callers check parent
+// directories separately, as an ignored directory cannot be re-included below.
+func (dr *DirRules) Match(relPath string, isDir bool) (*Pattern, error) {
+	p, err := dr.matchFiles(relPath, func(rules ruleFile) []Pattern { return rules.patterns }, isDir)
+	if p != nil || err != nil {
+		return p, err
+	}
+	return lastMatch(dr.base, relPath, isDir), nil
+}
+
+// MatchContents returns the contents-ignore pattern deciding whether a file at a
+// slash-separated path is captured as structure only, or nil if none matched
+func (dr *DirRules) MatchContents(relPath string) (*Pattern, error) {
+	return dr.matchFiles(relPath, func(rules ruleFile) []Pattern { return rules.contents }, false)
+}
+
+// matchFiles returns the last pattern selected from the files of the parent
+// directories of a path that matches it, searching deeper directories first
+func (dr *DirRules) matchFiles(relPath string, selectPatterns func(ruleFile) []Pattern, isDir bool) (*Pattern, error) {
+	for dir := path.Dir(relPath); ; dir = path.Dir(dir) {
+		if dir == "." {
+			dir = ""
+		}
+		rules, err := dr.rules(dir)
+		if err != nil {
+			return nil, err
+		}
+		if p := lastMatch(selectPatterns(rules), relPath, isDir); p != nil {
+			return p, nil
+		}
+		if dir == "" {
+			return nil, nil
+		}
+	}
+}
+
+// Predicates returns the size and type directives applying to a slash-separated
+// path, from the ignore files of its parent directories
+func (dr *DirRules) Predicates(relPath string) ([]Predicate, error) {
+	var predicates []Predicate
+	for dir := path.Dir(relPath); ; dir = path.Dir(dir) {
+		if dir == "." {
+			dir = ""
+		}
+		rules, err := dr.rules(dir)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, rules.predicates...)
+		if dir == "" {
+			break
+		}
+	}
+	return predicates, nil
+}
+
+// rules returns the rules of the ignore file in a directory, loading it on first use
+func (dr *DirRules) rules(dir string) (ruleFile, error) {
+	if rules, ok := dr.dirs[dir]; ok || dr.topDir == "" {
+		return rules, nil
+	}
+
+	filePath := filepath.Join(dr.topDir, filepath.FromSlash(dir), dr.fileName)
+	rules, err := loadRuleFile(filePath, dir, path.Join(dir, dr.fileName), dr.directives)
+	if err != nil {
+		return ruleFile{}, err
+	}
+	dr.dirs[dir] = rules
+	return rules, nil
+}
+
+// loadPatternFile reads the patterns of an ignore file relative to base, naming
+// the file source in them. A missing file has no patterns.
+func loadPatternFile(filePath, base, source string) ([]Pattern, error) {
+	rules, err := loadRuleFile(filePath, base, source, false)
+	return rules.patterns, err
+}
+
+// loadRuleFile reads the rules of an ignore file relative to base, including
+// its directives if they are allowed. A missing file has no rules.
+func loadRuleFile(filePath, base, source string, directives bool) (ruleFile, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ruleFile{}, nil
+		}
+		return ruleFile{}, fmt.Errorf("failed to open ignore file: %w", err)
+	}
+	defer file.Close()
+
+	rules, err := readRules(file, base, source, directives)
+	if err != nil {
+		return ruleFile{}, fmt.Errorf("failed to read ignore file %s: %w", filePath, err)
+	}
+	return rules, nil
+}
+
+// readPatterns reads the patterns of an ignore file relative to base
+func readPatterns(r io.Reader, base, source string) ([]Pattern, error) {
+	rules, err := readRules(r, base, source, false)
+	return rules.patterns, err
+}
+
+// readRules reads the rules of an ignore file relative to base, including its
+// size, type and contents-ignore directives if they are allowed
+func readRules(r io.Reader, base, source string, directives bool) (ruleFile, error) {
+	var rules ruleFile
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if directives {
+			if list, ok := strings.CutPrefix(strings.TrimSpace(text), contentsDirective); ok {
+				for _, pattern := range strings.Split(list, ",") {
+					if p, ok := ParsePattern(strings.TrimSpace(pattern), base); ok {
+						p.source, p.line = source, line
+						rules.contents = append(rules.contents, p)
+					}
+				}
+				continue
+			}
+
+			p, ok, err := ParsePredicate(text, base)
+			if err != nil {
+				return ruleFile{}, fmt.Errorf("%s:%d: %w", source, line, err)
+			}
+			if ok {
+				p.source, p.line = source, line
+				rules.predicates = append(rules.predicates, p)
+				continue
+			}
+		}
+
+		if p, ok := ParsePattern(text, base); ok {
+			p.source, p.line = source, line
+			rules.patterns = append(rules.patterns, p)
+		}
+	}
+	return rules, scanner.Err()
+}
+
+// GitIgnore applies the .gitignore files and .git/info/exclude of the git
+// repository containing a directory to the paths below that directory
+type GitIgnore struct {
+	rules  *DirRules
+	prefix string // Slash-separated path of the directory within the repository
+}
+
+// NewGitIgnore creates the git ignore rules for paths below rootDir. If rootDir
+// is not inside a git repository, only the .gitignore files below it apply.
+func NewGitIgnore(rootDir string) (*GitIgnore, error) {
+	absRoot, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory: %w", err)
+	}
+
+	repoRoot, gitDir := findRepository(absRoot)
+	if repoRoot == "" {
+		return &GitIgnore{rules: NewDirRules(absRoot, GitIgnoreFileName)}, nil
+	}
+
+	gi := &GitIgnore{rules: NewDirRules(repoRoot, GitIgnoreFileName)}
+	if rel, err := filepath.Rel(repoRoot, absRoot); err == nil && rel != "." {
+		gi.prefix = filepath.ToSlash(rel)
+	}
+
+	exclude, err := loadPatternFile(filepath.Join(gitDir, "info", "exclude"), "", ".git/info/exclude")
+	if err != nil {
+		return nil, err
+	}
+	gi.rules.base = exclude
+
+	return gi, nil
+}
+
+// Match returns the git pattern deciding whether a slash-separated path relative
+// to the root directory is ignored, or nil if none matched. Parent directories
+// must be checked first.
+func (gi *GitIgnore) Match(relPath string, isDir bool) (*Pattern, error) {
+	if gi.prefix != "" {
+		relPath = gi.prefix + "/" + relPath
+	}
+	return gi.rules.Match(relPath, isDir)
+}
+
+// findRepository finds the work tree root and git directory of the repository
+// containing dir, or empty strings if there is none
+func findRepository(dir string) (string, string) {
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return dir, dotGit
+			}
+			if gitDir := readGitFile(dotGit, dir); gitDir != "" {
+				return dir, gitDir
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// readGitFile resolves a .git file, as used by worktrees and submodules, to the
+// directory holding info/exclude
+func readGitFile(dotGit, workTree string) string {
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return ""
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return ""
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(workTree, gitDir)
+	}
+
+	// Linked worktrees share info/exclude with the main repository
+	if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir := strings.TrimSpace(string(common))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+		return commonDir
+	}
+	return gitDir
+}
@@ -1,173 +1,299 @@
 package ignore
 
 import (
-	"bufio"
-	"fmt"
-	"os"
+	"io"
 	"path/filepath"
 	"strings"
 )
 
 const IgnoreFileName = ".tmpltrignore"
 
-// IgnoreRules holds the rules for ignoring files and directories
+// IncludeFileName is the name of the file holding include patterns
+const IncludeFileName = ".tmpltrinclude"
+
+// IgnoreRules holds the rules for ignoring files and directories. Patterns have
+// gitignore semantics. tmpltr's own files are always ignored, whatever the other
+// patterns say. Otherwise, the extra patterns take precedence over the .tmpltrignore
+// files, of which deeper ones take precedence over the root one, which in turn
+// takes precedence over the default patterns. Within each, the last matching
+// pattern decides whether a path is ignored.
+//
+// If there are include patterns, files that are not ignored are only kept if
+// they match one, where again the last match wins and the extra include patterns
+// take precedence over the .tmpltrinclude file. Include patterns never apply to
+// directories, so every directory that is not ignored is searched.
+//
+// Files that are kept can still be ignored by size and type rules, given as
+// flags or as directives in .tmpltrignore files, which negated patterns do not
+// override. Contents-ignore patterns select files that are captured as
+// structure only.
 type IgnoreRules struct {
-	patterns []string
-	rootDir  string
+	patterns    []Pattern
+	predicates  []Predicate
+	contents    []Pattern // Extra patterns of files captured as structure only
+	control     []Pattern // Patterns of tmpltr's own files, which no pattern overrides
+	defaults    []Pattern
+	includes    []Pattern
+	includeFile []Pattern
+	files       *DirRules // .tmpltrignore files, nil unless loaded
+	rootDir     string
+	gitIgnore   *GitIgnore // .gitignore rules, nil unless enabled
 }
 
 // NewIgnoreRules creates a new IgnoreRules instance
 func NewIgnoreRules(rootDir string) *IgnoreRules {
 	return &IgnoreRules{
-		patterns: make([]string, 0),
+		patterns: make([]Pattern, 0),
 		rootDir:  rootDir,
 	}
 }
 
-// LoadIgnoreFile loads ignore patterns from .tmpltrignore file
+// LoadIgnoreFile enables the .tmpltrignore files of the root directory and of
+// every subdirectory, which are loaded as the directories are first visited and
+// apply to the subtree they are in
 func (ir *IgnoreRules) LoadIgnoreFile() error {
-	ignoreFilePath := filepath.Join(ir.rootDir, IgnoreFileName)
-	
-	file, err := os.Open(ignoreFilePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return fmt.Errorf("failed to open ignore file: %w", err)
-	}
-	defer file.Close()
+	ir.files = NewDirRules(ir.rootDir, IgnoreFileName)
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		
-		ir.patterns = append(ir.patterns, line)
-	}
+	// Load the root file up front so that errors in it are reported early
+	_, err := ir.files.Match(IgnoreFileName, false)
+	return err
+}
 
-	return scanner.Err()
+// LoadIgnoreReader loads the .tmpltrignore file of a slash-separated directory
+// ("" for the root) from a reader, for rules that do not scan a directory on disk
+func (ir *IgnoreRules) LoadIgnoreReader(dir string, r io.Reader) error {
+	if ir.files == nil {
+		ir.files = NewDirRules("", IgnoreFileName)
+	}
+	return ir.files.SetPatterns(dir, r)
 }
 
-// AddPattern adds a custom ignore pattern
-func (ir *IgnoreRules) AddPattern(pattern string) {
-	if pattern != "" {
-		ir.patterns = append(ir.patterns, pattern)
+// AddPattern adds a custom ignore pattern, naming where it came from in source;
+// blank patterns and comments are skipped
+func (ir *IgnoreRules) AddPattern(pattern, source string) {
+	if p, ok := ParsePattern(pattern, ""); ok {
+		p.source = source
+		ir.patterns = append(ir.patterns, p)
 	}
 }
 
 // AddPatterns adds multiple patterns from a slice
-func (ir *IgnoreRules) AddPatterns(patterns []string) {
+func (ir *IgnoreRules) AddPatterns(patterns []string, source string) {
 	for _, pattern := range patterns {
-		ir.AddPattern(pattern)
+		ir.AddPattern(pattern, source)
 	}
 }
 
+// AddIncludePatterns adds include patterns, naming where they came from in source
+func (ir *IgnoreRules) AddIncludePatterns(patterns []string, source string) {
+	for _, pattern := range patterns {
+		if p, ok := ParsePattern(pattern, ""); ok {
+			p.source = source
+			ir.includes = append(ir.includes, p)
+		}
+	}
+}
+
+// LoadIncludeFile loads include patterns from the root's .tmpltrinclude file
+func (ir *IgnoreRules) LoadIncludeFile() error {
+	patterns, err := loadPatternFile(filepath.Join(ir.rootDir, IncludeFileName), "", IncludeFileName)
+	if err != nil {
+		return err
+	}
+	ir.includeFile = patterns
+	return nil
+}
+
+// LoadIncludeReader loads include patterns in .tmpltrinclude format from a reader,
+// for rules that do not scan a directory on disk
+func (ir *IgnoreRules) LoadIncludeReader(r io.Reader) error {
+	patterns, err := readPatterns(r, "", IncludeFileName)
+	if err != nil {
+		return err
+	}
+	ir.includeFile = patterns
+	return nil
+}
+
+// controlPatterns match tmpltr's own files, which are never captured
+var controlPatterns = []string{
+	IgnoreFileName,
+	IncludeFileName,
+	".tmpltr.json",
+	".tmpltrsecrets",
+}
+
+// defaultPatterns are the common patterns applied unless disabled
+var defaultPatterns = []string{
+	".git/",
+	".svn/",
+	".hg/",
+	"node_modules/",
+	".DS_Store",
+	"Thumbs.db",
+	"*.tmp",
+	"*.temp",
+	"*.log",
+	"*.swp",
+	"*.swo",
+	"*~",
+}
+
 // AddDefaultPatterns adds common default ignore patterns
 func (ir *IgnoreRules) AddDefaultPatterns() {
-	defaultPatterns := []string{
-		".git/",
-		".svn/",
-		".hg/",
-		"node_modules/",
-		".DS_Store",
-		"Thumbs.db",
-		"*.tmp",
-		"*.temp",
-		"*.log",
-		"*.swp",
-		"*.swo",
-		"*~",
-		".tmpltrignore",
-	}
-	
-	ir.AddPatterns(defaultPatterns)
-}
-
-// ShouldIgnore checks if a file/directory should be ignored based on the rules
-func (ir *IgnoreRules) ShouldIgnore(filePath string) bool {
-	relPath, err := filepath.Rel(ir.rootDir, filePath)
-	if err != nil {
-		return false
+	ir.addDefaults(defaultPatterns, SourceDefault)
+}
+
+// AddControlPatterns adds the patterns ignoring tmpltr's own files, which apply
+// even when the default patterns are disabled and cannot be negated
+func (ir *IgnoreRules) AddControlPatterns() {
+	for _, pattern := range controlPatterns {
+		if p, ok := ParsePattern(pattern, ""); ok {
+			p.source = SourceDefault
+			ir.control = append(ir.control, p)
+		}
 	}
+}
 
-	relPath = filepath.ToSlash(relPath)
-	
-	for _, pattern := range ir.patterns {
-		if ir.matchesPattern(relPath, pattern) {
-			return true
+// addDefaults adds patterns with the precedence of the default patterns
+func (ir *IgnoreRules) addDefaults(patterns []string, source string) {
+	for _, pattern := range patterns {
+		if p, ok := ParsePattern(pattern, ""); ok {
+			p.source = source
+			ir.defaults = append(ir.defaults, p)
 		}
 	}
-	
-	return false
 }
 
-// matchesPattern checks if a file path matches an ignore pattern
-func (ir *IgnoreRules) matchesPattern(filePath, pattern string) bool {
-	pattern = filepath.ToSlash(pattern)
-	
-	// Handle directory patterns (ending with /)
-	if strings.HasSuffix(pattern, "/") {
-		dirPattern := strings.TrimSuffix(pattern, "/")
-		
-		if filePath == dirPattern {
-			return true
+// EnableGitIgnore additionally applies the .gitignore files at every directory
+// level and the .git/info/exclude file of the repository containing the root.
+// The tmpltr patterns take precedence over them.
+func (ir *IgnoreRules) EnableGitIgnore() error {
+	gitIgnore, err := NewGitIgnore(ir.rootDir)
+	if err != nil {
+		return err
+	}
+	ir.gitIgnore = gitIgnore
+	return nil
+}
+
+// Decision explains whether a path is ignored
+type Decision struct {
+	Ignored     bool
+	Pattern     *Pattern   // Pattern deciding the outcome, nil if no pattern matched
+	Parent      string     // Ignored parent directory the decision was inherited from, if any
+	NotIncluded bool       // File was left out because it matched no include pattern
+	Predicate   *Predicate // Size or type rule ignoring the file, if any
+}
+
+// ShouldIgnore checks if a file/directory should be ignored based on the rules.
+// Directories are checked before their contents, so the rules only decide about
+// the path itself.
+func (ir *IgnoreRules) ShouldIgnore(filePath string, isDir bool) (bool, error) {
+	decision, err := ir.Explain(filePath, isDir)
+	return decision.Ignored, err
+}
+
+// Explain evaluates the rules for a path itself, without its parent directories,
+// and returns the deciding pattern
+func (ir *IgnoreRules) Explain(filePath string, isDir bool) (Decision, error) {
+	relPath, err := filepath.Rel(ir.rootDir, filePath)
+	if err != nil || relPath == "." {
+		return Decision{}, nil
+	}
+
+	relPath = filepath.ToSlash(relPath)
+	pattern, err := ir.match(relPath, isDir)
+	if err != nil {
+		return Decision{}, err
+	}
+	if pattern != nil && !pattern.negate {
+		return Decision{Ignored: true, Pattern: pattern}, nil
+	}
+
+	// Files that are not ignored must also be selected by the include patterns
+	if !isDir && (len(ir.includes) > 0 || len(ir.includeFile) > 0) {
+		include := lastMatch(ir.includes, relPath, false)
+		if include == nil {
+			include = lastMatch(ir.includeFile, relPath, false)
 		}
-		
-		if strings.HasPrefix(filePath, dirPattern+"/") {
-			return true
+		if include == nil || include.negate {
+			return Decision{Ignored: true, Pattern: include, NotIncluded: true}, nil
 		}
-		
-		return false
-	}
-	
-	// Handle exact matches
-	if filePath == pattern {
-		return true
-	}
-	
-	// Handle wildcard patterns
-	if strings.Contains(pattern, "*") {
-		matched, err := filepath.Match(pattern, filepath.Base(filePath))
-		if err == nil && matched {
-			return true
+		return Decision{Pattern: include}, nil
+	}
+
+	return Decision{Pattern: pattern}, nil
+}
+
+// ExplainPath evaluates the rules for a path and each of its parent directories,
+// as a directory walk would, and returns the deciding pattern. A path below an
+// ignored directory is ignored regardless of its own patterns.
+func (ir *IgnoreRules) ExplainPath(filePath string, isDir bool) (Decision, error) {
+	relPath, err := filepath.Rel(ir.rootDir, filePath)
+	if err != nil || relPath == "." {
+		return Decision{}, nil
+	}
+
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	for i := 1; i < len(parts); i++ {
+		parent := strings.Join(parts[:i], "/")
+		decision, err := ir.Explain(filepath.Join(ir.rootDir, filepath.FromSlash(parent)), true)
+		if err != nil {
+			return Decision{}, err
 		}
-		
-		if strings.Contains(pattern, "/") {
-			matched, err := filepath.Match(pattern, filePath)
-			if err == nil && matched {
-				return true
-			}
+		if decision.Ignored {
+			decision.Parent = parent
+			return decision, nil
 		}
 	}
-	
-	// Handle path prefix patterns
-	if strings.Contains(pattern, "/") {
-		if strings.HasPrefix(filePath, pattern) {
-			return true
+
+	return ir.Explain(filePath, isDir)
+}
+
+// match returns the pattern deciding whether a slash-separated path is ignored
+func (ir *IgnoreRules) match(relPath string, isDir bool) (*Pattern, error) {
+	if p := lastMatch(ir.control, relPath, isDir); p != nil {
+		return p, nil
+	}
+
+	if p := lastMatch(ir.patterns, relPath, isDir); p != nil {
+		return p, nil
+	}
+
+	if ir.files != nil {
+		p, err := ir.files.Match(relPath, isDir)
+		if p != nil || err != nil {
+			return p, err
 		}
-	} else {
-		// Pattern is just a filename, check if any part of path matches
-		pathParts := strings.Split(filePath, "/")
-		for _, part := range pathParts {
-			if part == pattern {
-				return true
-			}
-			if strings.Contains(pattern, "*") {
-				matched, err := filepath.Match(pattern, part)
-				if err == nil && matched {
-					return true
-				}
-			}
+	}
+
+	if p := lastMatch(ir.defaults, relPath, isDir); p != nil {
+		return p, nil
+	}
+
+	if ir.gitIgnore != nil {
+		return ir.gitIgnore.Match(relPath, isDir)
+	}
+
+	return nil, nil
+}
+
+// lastMatch returns the last of a list of patterns matching a path, or nil
+func lastMatch(patterns []Pattern, relPath string, isDir bool) *Pattern {
+	for i := len(patterns) - 1; i >= 0; i-- {
+		if patterns[i].Match(relPath, isDir) {
+			return &patterns[i]
 		}
 	}
-	
-	return false
+	return nil
 }
 
-// GetPatterns returns all loaded patterns
+// GetPatterns returns the control, default and extra patterns
 func (ir *IgnoreRules) GetPatterns() []string {
-	return ir.patterns
-}
\ No newline at end of file
+	patterns := make([]string, 0, len(ir.control)+len(ir.defaults)+len(ir.patterns))
+	for _, p := range append(append(append([]Pattern{}, ir.control...), ir.defaults...), ir.patterns...) {
+		patterns = append(patterns, p.Text)
+	}
+	return patterns
+}
@@ -0,0 +1,43 @@
+package ignore
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestControlPatternsCannotBeNegated(t *testing.T) {
+	tests := []struct {
+		name       string
+		patterns   []string
+		ignoreFile string
+		path       string
+	}{
+		{"negated extension", []string{"*", "!*.json"}, "", ".tmpltr.json"},
+		{"negated name", []string{"!.tmpltrsecrets"}, "", ".tmpltrsecrets"},
+		{"negated in ignore file", nil, "!.tmpltrsecrets\n!.tmpltrinclude\n", ".tmpltrsecrets"},
+		{"negated in nested directory", nil, "!sub/.tmpltrignore\n", "sub/.tmpltrignore"},
+	}
+
+	root := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := NewIgnoreRules(root)
+			rules.AddControlPatterns()
+			rules.AddPatterns(tt.patterns, "test")
+			if tt.ignoreFile != "" {
+				if err := rules.LoadIgnoreReader("", strings.NewReader(tt.ignoreFile)); err != nil {
+					t.Fatalf("LoadIgnoreReader() error = %v", err)
+				}
+			}
+
+			decision, err := rules.ExplainPath(filepath.Join(root, filepath.FromSlash(tt.path)), false)
+			if err != nil {
+				t.Fatalf("ExplainPath() error = %v", err)
+			}
+			if !decision.Ignored || decision.Pattern == nil || decision.Pattern.Source() != SourceDefault {
+				t.Errorf("ExplainPath(%q) = %+v, want ignored by a default pattern", tt.path, decision)
+			}
+		})
+	}
+}
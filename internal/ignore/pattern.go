@@ -0,0 +1,300 @@
+package ignore
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// SourceDefault is the source of the built-in default patterns
+const SourceDefault = "default"
+
+// Pattern is a single ignore pattern with gitignore semantics
+type Pattern struct {
+	Text     string // Pattern as written, for display
+	source   string // Where the pattern was defined: a file, a flag or SourceDefault
+	line     int    // Line of the pattern in its source file, 0 if not from a file
+	base     string // Directory the pattern is relative to, slash-separated, "" for the root
+	glob     string // Glob matched against paths, without negation, anchoring or trailing slash
+	negate   bool   // Pattern starts with "!" and re-includes matching paths
+	dirOnly  bool   // Pattern ends with "/" and only matches directories
+	anchored bool   // Pattern contains a "/" and matches paths relative to base rather than names
+}
+
+// ParsePattern parses a line of an ignore file relative to the directory base
+// (slash-separated, "" for the root). It returns false for blank lines and comments.
+func ParsePattern(line, base string) (Pattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimTrailingSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Pattern{}, false
+	}
+
+	p := Pattern{Text: line, base: base}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") && !strings.HasSuffix(line, "\\/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		p.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return Pattern{}, false
+	}
+
+	p.glob = line
+	return p, true
+}
+
+// trimTrailingSpaces removes unescaped trailing spaces as git does
+func trimTrailingSpaces(line string) string {
+	end := len(line)
+	for end > 0 && line[end-1] == ' ' {
+		// Count the backslashes escaping this space
+		backslashes := 0
+		for i := end - 2; i >= 0 && line[i] == '\\'; i-- {
+			backslashes++
+		}
+		if backslashes%2 == 1 {
+			break
+		}
+		end--
+	}
+	return line[:end]
+}
+
+// Source describes where the pattern was defined, e.g. "sub/.tmpltrignore:3"
+func (p *Pattern) Source() string {
+	if p.line > 0 {
+		return fmt.Sprintf("%s:%d", p.source, p.line)
+	}
+	return p.source
+}
+
+// Negated reports whether the pattern re-includes the paths it matches
+func (p *Pattern) Negated() bool {
+	return p.negate
+}
+
+// Match reports whether the pattern matches a slash-separated path relative to
+// the root. isDir tells whether the path is a directory.
+func (p *Pattern) Match(relPath string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	// Patterns only apply to paths below the directory they were defined in
+	if p.base != "" {
+		if !strings.HasPrefix(relPath, p.base+"/") {
+			return false
+		}
+		relPath = relPath[len(p.base)+1:]
+	}
+
+	if p.anchored {
+		return wildmatch(p.glob, relPath)
+	}
+	return wildmatch(p.glob, path.Base(relPath))
+}
+
+// wildmatch matches a slash-separated path against a glob with gitignore
+// semantics: "*" and "?" do not match "/", and "**" between slashes matches
+// any number of directories
+func wildmatch(pattern, name string) bool {
+	return matchFrom(pattern, 0, name, 0)
+}
+
+// matchFrom matches pattern[pi:] against name[ni:]
+func matchFrom(pattern string, pi int, name string, ni int) bool {
+	for pi < len(pattern) {
+		switch c := pattern[pi]; c {
+		case '*':
+			start := pi
+			for pi < len(pattern) && pattern[pi] == '*' {
+				pi++
+			}
+			double := pi-start >= 2 && (start == 0 || pattern[start-1] == '/') &&
+				(pi == len(pattern) || pattern[pi] == '/')
+
+			if double {
+				// A trailing "**" matches everything below
+				if pi == len(pattern) {
+					return true
+				}
+				// "**/" matches zero or more leading directories
+				rest := pi + 1
+				if matchFrom(pattern, rest, name, ni) {
+					return true
+				}
+				for i := ni; i < len(name); i++ {
+					if name[i] == '/' && matchFrom(pattern, rest, name, i+1) {
+						return true
+					}
+				}
+				return false
+			}
+
+			// Any other run of stars matches within a single path component
+			for i := ni; ; i++ {
+				if matchFrom(pattern, pi, name, i) {
+					return true
+				}
+				if i >= len(name) || name[i] == '/' {
+					return false
+				}
+			}
+
+		case '?':
+			if ni >= len(name) || name[ni] == '/' {
+				return false
+			}
+			pi++
+			ni++
+
+		case '[':
+			matched, next, ok := matchClass(pattern, pi, name, ni)
+			if !ok {
+				// An unterminated class matches a literal "["
+				if ni >= len(name) || name[ni] != '[' {
+					return false
+				}
+				pi++
+				ni++
+				continue
+			}
+			if !matched {
+				return false
+			}
+			pi = next
+			ni++
+
+		case '\\':
+			if pi+1 < len(pattern) {
+				pi++
+			}
+			if ni >= len(name) || name[ni] != pattern[pi] {
+				return false
+			}
+			pi++
+			ni++
+
+		default:
+			if ni >= len(name) || name[ni] != c {
+				return false
+			}
+			pi++
+			ni++
+		}
+	}
+
+	return ni == len(name)
+}
+
+// matchClass matches a bracket expression starting at pattern[pi] against
+// name[ni]. It returns whether it matched, the index following the expression
+// and whether the expression was terminated.
+func matchClass(pattern string, pi int, name string, ni int) (bool, int, bool) {
+	i := pi + 1
+	negate := false
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		negate = true
+		i++
+	}
+
+	var ch byte
+	if ni < len(name) {
+		ch = name[ni]
+	}
+
+	matched := false
+	for first := true; ; first = false {
+		if i >= len(pattern) {
+			return false, 0, false
+		}
+		c := pattern[i]
+		if c == ']' && !first {
+			i++
+			break
+		}
+
+		// Named character classes such as [:digit:]
+		if c == '[' && i+1 < len(pattern) && pattern[i+1] == ':' {
+			if end := strings.Index(pattern[i+2:], ":]"); end >= 0 {
+				if namedClass(pattern[i+2:i+2+end], ch) {
+					matched = true
+				}
+				i += end + 4
+				continue
+			}
+		}
+
+		if c == '\\' && i+1 < len(pattern) {
+			i++
+			c = pattern[i]
+		}
+		i++
+
+		// Ranges such as a-z
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			hi := pattern[i+1]
+			i += 2
+			if hi == '\\' && i < len(pattern) {
+				hi = pattern[i]
+				i++
+			}
+			if c <= ch && ch <= hi {
+				matched = true
+			}
+			continue
+		}
+
+		if c == ch {
+			matched = true
+		}
+	}
+
+	if ni >= len(name) || ch == '/' {
+		return false, i, true
+	}
+	return matched != negate, i, true
+}
+
+// namedClass reports whether a byte belongs to a POSIX character class
+func namedClass(class string, c byte) bool {
+	isUpper := 'A' <= c && c <= 'Z'
+	isLower := 'a' <= c && c <= 'z'
+	isDigit := '0' <= c && c <= '9'
+	switch class {
+	case "alnum":
+		return isUpper || isLower || isDigit
+	case "alpha":
+		return isUpper || isLower
+	case "digit":
+		return isDigit
+	case "lower":
+		return isLower
+	case "upper":
+		return isUpper
+	case "xdigit":
+		return isDigit || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+	case "space":
+		return c == ' ' || '\t' <= c && c <= '\r'
+	case "blank":
+		return c == ' ' || c == '\t'
+	case "punct":
+		return '!' <= c && c <= '~' && !(isUpper || isLower || isDigit)
+	case "cntrl":
+		return c < ' ' || c == 0x7f
+	case "graph":
+		return '!' <= c && c <= '~'
+	case "print":
+		return ' ' <= c && c <= '~'
+	default:
+		return false
+	}
+}
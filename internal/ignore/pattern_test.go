@@ -0,0 +1,166 @@
+package ignore
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// The expected results below follow git check-ignore for the same pattern in a
+// .gitignore file of the base directory.
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		base    string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		// Patterns without a slash match names at any depth
+		{"*.log", "", "a.log", false, true},
+		{"*.log", "", "dir/a.log", false, true},
+		{"*.log", "", "a.log.txt", false, false},
+		{"foo", "", "foo", false, true},
+		{"foo", "", "a/b/foo", true, true},
+		{"foo", "", "foobar", false, false},
+
+		// Directory-only patterns
+		{"foo/", "", "foo", false, false},
+		{"foo/", "", "foo", true, true},
+		{"foo/", "", "a/foo", true, true},
+		{"a/foo/", "", "a/foo", true, true},
+		{"a/foo/", "", "a/foo", false, false},
+
+		// A leading or inner slash anchors the pattern to its base directory
+		{"/foo", "", "foo", false, true},
+		{"/foo", "", "a/foo", false, false},
+		{"a/b", "", "a/b", false, true},
+		{"a/b", "", "x/a/b", false, false},
+		{"doc/*.txt", "", "doc/a.txt", false, true},
+		{"doc/*.txt", "", "doc/x/a.txt", false, false},
+
+		// Anchored patterns match whole paths, not prefixes of names
+		{"src/a", "", "src/a", false, true},
+		{"src/a", "", "src/abc.go", false, false},
+		{"/src", "", "src.go", false, false},
+
+		// Patterns of nested ignore files apply below their directory only
+		{"*.log", "sub", "sub/a.log", false, true},
+		{"*.log", "sub", "sub/x/a.log", false, true},
+		{"*.log", "sub", "a.log", false, false},
+		{"*.log", "sub", "subx/a.log", false, false},
+		{"/x", "sub", "sub/x", false, true},
+		{"/x", "sub", "sub/y/x", false, false},
+		{"x/y", "sub", "sub/x/y", false, true},
+
+		// Double asterisks
+		{"**/foo", "", "foo", false, true},
+		{"**/foo", "", "a/b/foo", false, true},
+		{"**/foo/bar", "", "a/foo/bar", false, true},
+		{"a/**/b", "", "a/b", false, true},
+		{"a/**/b", "", "a/x/y/b", false, true},
+		{"a/**/b", "", "ab/b", false, false},
+		{"abc/**", "", "abc/x", false, true},
+		{"abc/**", "", "abc/x/y", false, true},
+		{"abc/**", "", "abc", true, false},
+
+		// Single asterisks and question marks do not match slashes
+		{"a*/b", "", "ax/b", false, true},
+		{"a*/b", "", "a/x/b", false, false},
+		{"?.txt", "", "a.txt", false, true},
+		{"?.txt", "", "ab.txt", false, false},
+		{"a?b", "", "a/b", false, false},
+
+		// Bracket expressions
+		{"[abc].txt", "", "b.txt", false, true},
+		{"[abc].txt", "", "d.txt", false, false},
+		{"[!abc].txt", "", "d.txt", false, true},
+		{"[a-c]x", "", "bx", false, true},
+		{"[a-c]x", "", "dx", false, false},
+		{"[[:digit:]].txt", "", "5.txt", false, true},
+		{"[[:digit:]].txt", "", "a.txt", false, false},
+
+		// Escapes
+		{`\#foo`, "", "#foo", false, true},
+		{`\!foo`, "", "!foo", false, true},
+		{`\*`, "", "*", false, true},
+		{`\*`, "", "a", false, false},
+		{`foo\ `, "", "foo ", false, true},
+		{`foo\ `, "", "foo", false, false},
+		{"foo  ", "", "foo", false, true},
+
+		// Negated patterns match the paths they re-include
+		{"!foo", "", "foo", false, true},
+		{"!*.log", "", "a/b.log", false, true},
+	}
+
+	for _, tt := range tests {
+		p, ok := ParsePattern(tt.pattern, tt.base)
+		if !ok {
+			t.Errorf("ParsePattern(%q) was skipped", tt.pattern)
+			continue
+		}
+		if got := p.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("pattern %q in %q matching %q (dir %v) = %v, want %v", tt.pattern, tt.base, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		line    string
+		ok      bool
+		negated bool
+	}{
+		{"", false, false},
+		{"   ", false, false},
+		{"# comment", false, false},
+		{`\#foo`, true, false},
+		{"!foo", true, true},
+		{`\!foo`, true, false},
+		{"/", false, false},
+		{"foo\r", true, false},
+	}
+
+	for _, tt := range tests {
+		p, ok := ParsePattern(tt.line, "")
+		if ok != tt.ok || p.Negated() != tt.negated {
+			t.Errorf("ParsePattern(%q) = ok %v, negated %v, want ok %v, negated %v", tt.line, ok, p.Negated(), tt.ok, tt.negated)
+		}
+	}
+}
+
+func TestIgnoreRulesNegation(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{"negation re-includes", []string{"*.log", "!keep.log"}, "keep.log", false, false},
+		{"negation leaves others", []string{"*.log", "!keep.log"}, "a.log", false, true},
+		{"last match wins", []string{"!keep.log", "*.log"}, "keep.log", false, true},
+		{"file in ignored directory", []string{"build/", "!build/keep.txt"}, "build/keep.txt", false, true},
+		{"file in ignored directory contents", []string{"build/*", "!build/keep.txt"}, "build/keep.txt", false, false},
+		{"other file in ignored directory contents", []string{"build/*", "!build/keep.txt"}, "build/x.txt", false, true},
+		{"negated directory", []string{"out/", "!out/"}, "out/a.txt", false, false},
+		{"nested directory of ignored contents", []string{"build/*", "!build/sub/"}, "build/sub/a.txt", false, false},
+	}
+
+	root := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := NewIgnoreRules(root)
+			rules.AddPatterns(tt.patterns, "test")
+
+			decision, err := rules.ExplainPath(filepath.Join(root, filepath.FromSlash(tt.path)), tt.isDir)
+			if err != nil {
+				t.Fatalf("ExplainPath() error = %v", err)
+			}
+			if decision.Ignored != tt.want {
+				t.Errorf("ExplainPath(%q) ignored = %v, want %v", tt.path, decision.Ignored, tt.want)
+			}
+		})
+	}
+}
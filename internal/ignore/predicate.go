@@ -0,0 +1,166 @@
+package ignore
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tmpltr/internal/textdiff"
+)
+
+// HeadSize is how many leading bytes of a file are inspected by type rules
+const HeadSize = 8000
+
+// Prefixes of the size and type directives of .tmpltrignore files
+const (
+	sizeDirective = "size>"
+	typeDirective = "type:"
+)
+
+// Predicate is a rule ignoring files by their size or contents rather than their
+// path, written as "size>50MB" or "type:binary" in .tmpltrignore files
+type Predicate struct {
+	Text    string // Rule as written, for display
+	source  string // Where the rule was defined: a file or a flag
+	line    int    // Line of the rule in its source file, 0 if not from a file
+	base    string // Directory the rule applies below, slash-separated, "" for the root
+	maxSize int64  // Files larger than this are ignored, -1 if not a size rule
+	binary  bool   // Binary files are ignored
+}
+
+// ParsePredicate parses a size or type directive of an ignore file in the
+// directory base. It returns false if the line is not a directive.
+func ParsePredicate(line, base string) (Predicate, bool, error) {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, sizeDirective):
+		size, err := ParseSize(strings.TrimPrefix(line, sizeDirective))
+		if err != nil {
+			return Predicate{}, true, err
+		}
+		return Predicate{Text: line, base: base, maxSize: size}, true, nil
+
+	case strings.HasPrefix(line, typeDirective):
+		fileType := strings.TrimSpace(strings.TrimPrefix(line, typeDirective))
+		if fileType != "binary" {
+			return Predicate{}, true, fmt.Errorf("unknown file type %q (available: binary)", fileType)
+		}
+		return Predicate{Text: line, base: base, maxSize: -1, binary: true}, true, nil
+	}
+
+	return Predicate{}, false, nil
+}
+
+// Source describes where the rule was defined, e.g. "sub/.tmpltrignore:3"
+func (p *Predicate) Source() string {
+	if p.line > 0 {
+		return fmt.Sprintf("%s:%d", p.source, p.line)
+	}
+	return p.source
+}
+
+// Match reports whether the rule ignores a file at a slash-separated path relative
+// to the root. head returns the beginning of the file and is only called for type rules.
+func (p *Predicate) Match(relPath string, size int64, head func() ([]byte, error)) (bool, error) {
+	if p.base != "" && !strings.HasPrefix(relPath, p.base+"/") {
+		return false, nil
+	}
+
+	if p.maxSize >= 0 && size > p.maxSize {
+		return true, nil
+	}
+	if p.binary {
+		content, err := head()
+		if err != nil {
+			return false, err
+		}
+		return textdiff.IsBinary(content), nil
+	}
+	return false, nil
+}
+
+// AddMaxFileSize ignores files larger than a size such as "10MB", naming where
+// the rule came from in source
+func (ir *IgnoreRules) AddMaxFileSize(size, source string) error {
+	p, _, err := ParsePredicate(sizeDirective+size, "")
+	if err != nil {
+		return err
+	}
+	p.source = source
+	ir.predicates = append(ir.predicates, p)
+	return nil
+}
+
+// AddExcludeBinary ignores binary files, naming where the rule came from in source
+func (ir *IgnoreRules) AddExcludeBinary(source string) {
+	ir.predicates = append(ir.predicates, Predicate{
+		Text:    typeDirective + "binary",
+		source:  source,
+		maxSize: -1,
+		binary:  true,
+	})
+}
+
+// ExplainFile evaluates the size and type rules for a file that the patterns do
+// not ignore. head returns the beginning of the file's contents; it is called at
+// most once, and only if a type rule applies to the file.
+func (ir *IgnoreRules) ExplainFile(filePath string, size int64, head func() ([]byte, error)) (Decision, error) {
+	relPath, err := filepath.Rel(ir.rootDir, filePath)
+	if err != nil || relPath == "." {
+		return Decision{}, nil
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	predicates := ir.predicates
+	if ir.files != nil {
+		filePredicates, err := ir.files.Predicates(relPath)
+		if err != nil {
+			return Decision{}, err
+		}
+		predicates = append(append([]Predicate{}, predicates...), filePredicates...)
+	}
+	if len(predicates) == 0 {
+		return Decision{}, nil
+	}
+
+	// Read the head of the file only once, however many type rules there are
+	var content []byte
+	var headErr error
+	read := false
+	cachedHead := func() ([]byte, error) {
+		if !read {
+			content, headErr = head()
+			read = true
+		}
+		return content, headErr
+	}
+
+	for i := range predicates {
+		matched, err := predicates[i].Match(relPath, size, cachedHead)
+		if err != nil {
+			return Decision{}, err
+		}
+		if matched {
+			return Decision{Ignored: true, Predicate: &predicates[i]}, nil
+		}
+	}
+	return Decision{}, nil
+}
+
+// ReadHead reads the first HeadSize bytes of a file, or all of a shorter file
+func ReadHead(filePath string) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	buf := make([]byte, HeadSize)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+	return buf[:n], nil
+}
@@ -0,0 +1,92 @@
+package ignore
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Preset is a named set of ignore patterns for the build output and caches of an ecosystem
+type Preset struct {
+	Name     string
+	Markers  []string // Glob patterns of files in the root that identify the ecosystem
+	Patterns []string
+}
+
+// presets are the built-in presets, by name
+var presets = map[string]Preset{
+	"go": {
+		Name:     "go",
+		Markers:  []string{"go.mod", "go.work"},
+		Patterns: []string{"/bin/", "/dist/", "*.test", "*.out", "*.prof"},
+	},
+	"node": {
+		Name:    "node",
+		Markers: []string{"package.json"},
+		Patterns: []string{
+			"node_modules/", ".npm/", ".pnpm-store/", ".yarn/cache/", ".yarn/install-state.gz",
+			"dist/", "build/", "coverage/", ".next/", ".nuxt/", ".turbo/", ".parcel-cache/",
+			".eslintcache", "npm-debug.log*", "yarn-error.log*",
+		},
+	},
+	"python": {
+		Name:    "python",
+		Markers: []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements.txt", "Pipfile"},
+		Patterns: []string{
+			"__pycache__/", "*.py[cod]", ".venv/", "venv/", ".tox/", ".nox/", "*.egg-info/",
+			".pytest_cache/", ".mypy_cache/", ".ruff_cache/", "build/", "dist/", ".coverage", "htmlcov/",
+		},
+	},
+	"terraform": {
+		Name:     "terraform",
+		Markers:  []string{"*.tf"},
+		Patterns: []string{".terraform/", "*.tfstate", "*.tfstate.*", "*.tfplan", "crash.log", "crash.*.log"},
+	},
+	"jetbrains": {
+		Name:     "jetbrains",
+		Markers:  []string{".idea"},
+		Patterns: []string{".idea/", "*.iml", "*.ipr", "*.iws", "/out/"},
+	},
+}
+
+// PresetNames returns the names of the built-in presets, sorted
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AddPreset adds the patterns of a built-in preset with the precedence of the
+// default patterns
+func (ir *IgnoreRules) AddPreset(name string) error {
+	preset, ok := presets[name]
+	if !ok {
+		return fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(PresetNames(), ", "))
+	}
+
+	ir.addDefaults(preset.Patterns, "preset "+preset.Name)
+	return nil
+}
+
+// DetectPresets returns the names of the presets whose marker files exist in
+// the root directory, sorted
+func DetectPresets(rootDir string) ([]string, error) {
+	var detected []string
+	for _, name := range PresetNames() {
+		for _, marker := range presets[name].Markers {
+			matches, err := filepath.Glob(filepath.Join(rootDir, marker))
+			if err != nil {
+				return nil, fmt.Errorf("failed to detect presets: %w", err)
+			}
+			if len(matches) > 0 {
+				detected = append(detected, name)
+				break
+			}
+		}
+	}
+	return detected, nil
+}
@@ -0,0 +1,54 @@
+package ignore
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits are the multipliers of the size suffixes accepted by ParseSize, longest first
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as "512", "100KB" or "1.5GB", with binary units
+func ParseSize(s string) (int64, error) {
+	text := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(text, unit.suffix) {
+			text = strings.TrimSpace(strings.TrimSuffix(text, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 500KB, 10MB or 1GB)", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// FormatSize formats a size in bytes with the largest fitting binary unit
+func FormatSize(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
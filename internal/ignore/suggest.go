@@ -0,0 +1,238 @@
+package ignore
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"tmpltr/internal/textdiff"
+)
+
+// secretPatterns match the names of files that commonly hold credentials
+var secretPatterns = []string{
+	".env", ".env.local", ".env.*.local", ".env.production",
+	"*.pem", "*.key", "*.p12", "*.pfx", "*.keystore", "*.jks",
+	"id_rsa", "id_dsa", "id_ecdsa", "id_ed25519",
+	".npmrc", ".pypirc", ".netrc", "credentials.json", "*.tfvars",
+}
+
+// SuggestOptions are the thresholds used by Suggest
+type SuggestOptions struct {
+	LargeFileSize int64 // Binary files at least this large are suggested
+	ManyFiles     int   // Directories holding at least this many files are suggested
+}
+
+// Suggestion is an ignore pattern proposed for a directory
+type Suggestion struct {
+	Pattern string // Pattern for the .tmpltrignore file
+	Reason  string // Why the pattern is proposed
+	Files   int    // Number of files the pattern excludes that no earlier suggestion does
+	Size    int64  // Total size of those files
+}
+
+// suggestFile is a file considered by Suggest
+type suggestFile struct {
+	relPath string // Slash-separated path relative to the root
+	size    int64
+	binary  bool // Whether the file is large and looks binary
+	covered bool // Whether an earlier suggestion excludes the file
+}
+
+// Suggest inspects a directory and proposes ignore patterns for what the default
+// patterns miss: the build output and caches of detected ecosystems, files that
+// commonly hold secrets, directories holding many files and large binary files.
+// Each suggestion reports the files it excludes beyond the earlier ones.
+func Suggest(rootDir string, opts SuggestOptions) ([]Suggestion, error) {
+	files, err := scanSuggestFiles(rootDir, opts.LargeFileSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var suggestions []Suggestion
+	seen := make(map[string]bool)
+	add := func(pattern, reason string) {
+		if seen[pattern] {
+			return
+		}
+		seen[pattern] = true
+		if s, ok := coverFiles(files, pattern, reason); ok {
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	// Build output and caches of the detected ecosystems
+	presetNames, err := DetectPresets(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range presetNames {
+		for _, pattern := range presets[name].Patterns {
+			add(pattern, "build output and caches ("+name+" preset)")
+		}
+	}
+
+	// Files that commonly hold secrets
+	for _, pattern := range secretPatterns {
+		add(pattern, "may contain secrets")
+	}
+
+	// Directories holding many files, the deepest ones so that a source tree is
+	// only suggested when no subdirectory accounts for the files
+	for _, dir := range manyFileDirs(files, opts.ManyFiles) {
+		add("/"+escapePattern(dir)+"/", "directory with many files")
+	}
+
+	// Large binary files
+	for i := range files {
+		if files[i].binary && !files[i].covered {
+			add("/"+escapePattern(files[i].relPath), "large binary file")
+		}
+	}
+
+	return suggestions, nil
+}
+
+// scanSuggestFiles lists the regular files below rootDir not excluded by the
+// default patterns, checking files of at least largeSize if they are binary
+func scanSuggestFiles(rootDir string, largeSize int64) ([]suggestFile, error) {
+	rules := NewIgnoreRules(rootDir)
+	rules.AddControlPatterns()
+	rules.AddDefaultPatterns()
+
+	var files []suggestFile
+	err := filepath.WalkDir(rootDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("error walking directory: %w", err)
+		}
+
+		ignored, err := rules.ShouldIgnore(filePath, d.IsDir())
+		if err != nil {
+			return err
+		}
+		if ignored {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", filePath, err)
+		}
+		relPath, err := filepath.Rel(rootDir, filePath)
+		if err != nil {
+			return fmt.Errorf("failed to calculate relative path: %w", err)
+		}
+
+		file := suggestFile{relPath: filepath.ToSlash(relPath), size: info.Size()}
+		if largeSize > 0 && file.size >= largeSize {
+			if file.binary, err = isBinaryFile(filePath); err != nil {
+				return err
+			}
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// isBinaryFile reports whether the beginning of a file looks like binary data
+func isBinaryFile(filePath string) (bool, error) {
+	head, err := ReadHead(filePath)
+	if err != nil {
+		return false, err
+	}
+	return textdiff.IsBinary(head), nil
+}
+
+// coverFiles marks the files not yet covered that a pattern excludes, directly
+// or through a parent directory, and returns the suggestion if there are any
+func coverFiles(files []suggestFile, pattern, reason string) (Suggestion, bool) {
+	p, ok := ParsePattern(pattern, "")
+	if !ok {
+		return Suggestion{}, false
+	}
+
+	s := Suggestion{Pattern: pattern, Reason: reason}
+	for i := range files {
+		if files[i].covered || !matchesPathOrParent(&p, files[i].relPath) {
+			continue
+		}
+		files[i].covered = true
+		s.Files++
+		s.Size += files[i].size
+	}
+	return s, s.Files > 0
+}
+
+// matchesPathOrParent reports whether a pattern matches a file or any of its parent directories
+func matchesPathOrParent(p *Pattern, relPath string) bool {
+	if p.Match(relPath, false) {
+		return true
+	}
+	for dir := path.Dir(relPath); dir != "."; dir = path.Dir(dir) {
+		if p.Match(dir, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// manyFileDirs returns the directories holding at least threshold of the files
+// not yet covered, none of whose subdirectories do, sorted by path
+func manyFileDirs(files []suggestFile, threshold int) []string {
+	if threshold <= 0 {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, file := range files {
+		if file.covered {
+			continue
+		}
+		for dir := path.Dir(file.relPath); dir != "."; dir = path.Dir(dir) {
+			counts[dir]++
+		}
+	}
+
+	// A directory qualifies unless one of its subdirectories does as well
+	hasLargeChild := make(map[string]bool)
+	for dir, count := range counts {
+		if count >= threshold {
+			if parent := path.Dir(dir); parent != "." {
+				hasLargeChild[parent] = true
+			}
+		}
+	}
+
+	var dirs []string
+	for dir, count := range counts {
+		if count >= threshold && !hasLargeChild[dir] {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// escapePattern escapes the glob characters of a literal path for use in a pattern
+func escapePattern(literal string) string {
+	var b strings.Builder
+	for _, c := range literal {
+		if strings.ContainsRune(`\*?[`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
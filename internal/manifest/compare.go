@@ -0,0 +1,71 @@
+package manifest
+
+import "sort"
+
+// ChangeKind describes how a file differs between two manifests
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"    // File exists only in the new manifest
+	ChangeModified ChangeKind = "modified" // File exists in both manifests with different contents
+	ChangeRemoved  ChangeKind = "removed"  // File exists only in the old manifest
+)
+
+// Change is a single file difference between two manifests
+type Change struct {
+	Path string     // Relative path of the file
+	Kind ChangeKind // How the file changed
+	Old  *FileEntry // Entry in the old manifest, nil if added
+	New  *FileEntry // Entry in the new manifest, nil if removed
+}
+
+// Compare compares two manifests by path and hash and returns the changes needed
+// to turn old into new, sorted by path
+func Compare(old, new *Manifest) []Change {
+	oldFiles := make(map[string]*FileEntry, len(old.Files))
+	for i := range old.Files {
+		oldFiles[old.Files[i].OriginalPath] = &old.Files[i]
+	}
+
+	var changes []Change
+	newFiles := make(map[string]bool, len(new.Files))
+	for i := range new.Files {
+		entry := &new.Files[i]
+		newFiles[entry.OriginalPath] = true
+
+		oldEntry, ok := oldFiles[entry.OriginalPath]
+		if !ok {
+			changes = append(changes, Change{Path: entry.OriginalPath, Kind: ChangeAdded, New: entry})
+		} else if oldEntry.Hash != entry.Hash || oldEntry.IncludeContents != entry.IncludeContents {
+			changes = append(changes, Change{Path: entry.OriginalPath, Kind: ChangeModified, Old: oldEntry, New: entry})
+		}
+	}
+
+	for i := range old.Files {
+		entry := &old.Files[i]
+		if !newFiles[entry.OriginalPath] {
+			changes = append(changes, Change{Path: entry.OriginalPath, Kind: ChangeRemoved, Old: entry})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
+
+// CountChanges returns the number of added, modified and removed files in changes
+func CountChanges(changes []Change) (added, modified, removed int) {
+	for _, change := range changes {
+		switch change.Kind {
+		case ChangeAdded:
+			added++
+		case ChangeModified:
+			modified++
+		case ChangeRemoved:
+			removed++
+		}
+	}
+	return added, modified, removed
+}
@@ -4,20 +4,40 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
-// SaveManifest saves a manifest to the specified file path
+// SaveManifest saves a manifest to the specified file path.
+// The file is replaced atomically, so readers see either the previous or the new manifest.
 func SaveManifest(manifest *Manifest, filePath string) error {
 	data, err := json.MarshalIndent(manifest, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal manifest: %w", err)
 	}
 
-	err = os.WriteFile(filePath, data, 0644)
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), ".tmp-manifest-*")
 	if err != nil {
 		return fmt.Errorf("failed to write manifest file: %w", err)
 	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write manifest file: %w", err)
+	}
+	if err := tmpFile.Chmod(0644); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write manifest file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write manifest file: %w", err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), filePath); err != nil {
+		return fmt.Errorf("failed to write manifest file: %w", err)
+	}
 
 	return nil
 }
@@ -69,10 +89,24 @@ func ValidateManifest(manifest *Manifest) error {
 		}
 		pathSet[file.OriginalPath] = true
 
-		if filepath.IsAbs(file.OriginalPath) {
-			return fmt.Errorf("file path must be relative: %s", file.OriginalPath)
+		if err := ValidatePath(file.OriginalPath); err != nil {
+			return err
 		}
 	}
 
+	return nil
+}
+
+// ValidatePath checks that a file path of a manifest is relative and stays inside
+// the directory the template is restored to, as manifests of bundles and foreign
+// template directories cannot be trusted
+func ValidatePath(originalPath string) error {
+	cleaned := path.Clean(strings.ReplaceAll(originalPath, "\\", "/"))
+	if filepath.IsAbs(originalPath) || path.IsAbs(cleaned) || filepath.VolumeName(originalPath) != "" {
+		return fmt.Errorf("file path must be relative: %s", originalPath)
+	}
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return fmt.Errorf("file path must stay inside the template: %s", originalPath)
+	}
 	return nil
 }
\ No newline at end of file
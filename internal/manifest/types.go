@@ -1,22 +1,74 @@
 package manifest
 
-import "time"
+import (
+	"io/fs"
+	"time"
+)
+
+// DefaultFileMode is the mode of restored files whose original mode was not recorded
+const DefaultFileMode fs.FileMode = 0644
 
 // FileEntry represents a single file in the template manifest
 type FileEntry struct {
-	OriginalPath    string `json:"original_path"`    // Relative path of the file in the source directory
-	Hash            string `json:"hash"`             // SHA256 hash used for lookup in the files/ store
-	IncludeContents bool   `json:"include_contents"` // Boolean flag indicating whether file contents were saved
-	Compressed      bool   `json:"compressed"`       // Boolean flag indicating whether file content is compressed
-	OriginalSize    int64  `json:"original_size"`    // Original file size in bytes
-	StoredSize      int64  `json:"stored_size"`      // Stored file size in bytes (after compression if applicable)
+	OriginalPath    string `json:"original_path"`         // Relative path of the file in the source directory
+	Hash            string `json:"hash"`                  // SHA256 hash used for lookup in the files/ store
+	IncludeContents bool   `json:"include_contents"`      // Boolean flag indicating whether file contents were saved
+	Compressed      bool   `json:"compressed"`            // Boolean flag indicating whether file content is compressed
+	OriginalSize    int64  `json:"original_size"`         // Original file size in bytes
+	StoredSize      int64  `json:"stored_size"`           // Stored file size in bytes (after compression if applicable)
+	Mode            uint32 `json:"mode,omitempty"`        // Permission bits of the original file, if recorded
+	Templated       bool   `json:"templated,omitempty"`   // Whether the contents hold variable placeholders rendered on restore
+	Placeholder     string `json:"placeholder,omitempty"` // Placeholder restored for a structure-only file, empty for an empty file
+}
+
+// Placeholders restored for structure-only files, selected by make --placeholder
+const (
+	PlaceholderEmpty    = "empty"    // An empty file
+	PlaceholderHeader   = "header"   // The first lines of the file, stored in the template
+	PlaceholderSkeleton = "skeleton" // The declarations of a Go file, stored in the template
+	PlaceholderSized    = "sized"    // A sparse file of the original size
+)
+
+// StoresContents reports whether the entry references stored contents: those of
+// the file or, for a structure-only file, those of its placeholder
+func (f *FileEntry) StoresContents() bool {
+	return f.IncludeContents || f.Placeholder == PlaceholderHeader || f.Placeholder == PlaceholderSkeleton
+}
+
+// FileMode returns the permission bits to restore the file with
+func (f *FileEntry) FileMode() fs.FileMode {
+	if f.Mode == 0 {
+		return DefaultFileMode
+	}
+	return fs.FileMode(f.Mode).Perm()
+}
+
+// Source records the git commit a template version was captured from
+type Source struct {
+	Repository string `json:"repository"`    // Path or URL of the repository
+	Ref        string `json:"ref,omitempty"` // Ref that was requested, e.g. "v1.4.0"
+	Commit     string `json:"commit"`        // Full SHA of the captured commit
+}
+
+// Variable is a value supplied when a template is restored, referenced by a
+// placeholder in the contents of templated files
+type Variable struct {
+	Name        string `json:"name"`                  // Name used in placeholders and as environment variable
+	Description string `json:"description,omitempty"` // What the value is, shown when prompting for it
+	Required    bool   `json:"required,omitempty"`    // Whether a value must be supplied
+	Sensitive   bool   `json:"sensitive,omitempty"`   // Whether the value is secret and must not be echoed or recorded
 }
 
 // Manifest represents the complete template manifest structure
 type Manifest struct {
-	Name      string      `json:"name"`       // Template name
-	CreatedAt time.Time   `json:"created_at"` // Template creation timestamp
-	Files     []FileEntry `json:"files"`      // List of files in the template
+	Name      string      `json:"name"`                // Template name
+	Version   int         `json:"version,omitempty"`   // Version number of this manifest revision, starting at 1
+	Tag       string      `json:"tag,omitempty"`       // Optional tag of this version, e.g. "v2"
+	CreatedAt time.Time   `json:"created_at"`          // Template creation timestamp
+	UpdatedAt time.Time   `json:"updated_at,omitzero"` // Timestamp of the last update, if the template was updated
+	Source    *Source     `json:"source,omitempty"`    // Git commit the files were captured from, if any
+	Files     []FileEntry `json:"files"`               // List of files in the template
+	Variables []Variable  `json:"variables,omitempty"` // Variables referenced by templated files
 }
 
 // NewManifest creates a new manifest with the given name
@@ -28,6 +80,14 @@ func NewManifest(name string) *Manifest {
 	}
 }
 
+// VersionTime returns the time this version of the template was created
+func (m *Manifest) VersionTime() time.Time {
+	if !m.UpdatedAt.IsZero() {
+		return m.UpdatedAt
+	}
+	return m.CreatedAt
+}
+
 // AddFile adds a new file entry to the manifest
 func (m *Manifest) AddFile(originalPath, hash string, includeContents, compressed bool, originalSize, storedSize int64) {
 	entry := FileEntry{
@@ -41,6 +101,11 @@ func (m *Manifest) AddFile(originalPath, hash string, includeContents, compresse
 	m.Files = append(m.Files, entry)
 }
 
+// AddEntry adds a complete file entry to the manifest
+func (m *Manifest) AddEntry(entry FileEntry) {
+	m.Files = append(m.Files, entry)
+}
+
 // GetFileByPath returns the file entry for the given original path
 func (m *Manifest) GetFileByPath(originalPath string) *FileEntry {
 	for i := range m.Files {
@@ -88,4 +153,4 @@ func (m *Manifest) GetCompressionRatio() float64 {
 		return 0
 	}
 	return float64(storedSize) / float64(originalSize)
-}
\ No newline at end of file
+}
@@ -0,0 +1,69 @@
+package manifest
+
+import "bytes"
+
+// Delimiters of variable placeholders
+const (
+	placeholderOpen  = "{{ ."
+	placeholderClose = " }}"
+)
+
+// Placeholder returns the text standing for a variable in templated files
+func Placeholder(name string) string {
+	return placeholderOpen + name + placeholderClose
+}
+
+// AddVariable registers a variable, replacing an earlier one with the same name
+func (m *Manifest) AddVariable(v Variable) {
+	for i := range m.Variables {
+		if m.Variables[i].Name == v.Name {
+			m.Variables[i] = v
+			return
+		}
+	}
+	m.Variables = append(m.Variables, v)
+}
+
+// GetVariable returns the variable with the given name, or nil
+func (m *Manifest) GetVariable(name string) *Variable {
+	for i := range m.Variables {
+		if m.Variables[i].Name == name {
+			return &m.Variables[i]
+		}
+	}
+	return nil
+}
+
+// Render replaces the placeholders of the given variables in the contents of a
+// templated file in a single pass, so that values are never rendered again.
+// Placeholders of other names are left as they are.
+func Render(content []byte, values map[string]string) []byte {
+	var out bytes.Buffer
+	for {
+		start := bytes.Index(content, []byte(placeholderOpen))
+		if start < 0 {
+			break
+		}
+		end := bytes.Index(content[start:], []byte(placeholderClose))
+		if end < 0 {
+			break
+		}
+		end += start + len(placeholderClose)
+
+		// Text that is not a known placeholder is kept, looking for one inside it
+		name := string(content[start+len(placeholderOpen) : end-len(placeholderClose)])
+		value, ok := values[name]
+		if !ok {
+			end = start + len(placeholderOpen)
+		}
+		out.Write(content[:start])
+		if ok {
+			out.WriteString(value)
+		} else {
+			out.Write(content[start:end])
+		}
+		content = content[end:]
+	}
+	out.Write(content)
+	return out.Bytes()
+}
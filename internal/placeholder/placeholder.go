@@ -0,0 +1,43 @@
+package placeholder
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"tmpltr/internal/textdiff"
+)
+
+// MaxHeaderSize limits a header placeholder, so that files with long lines,
+// such as minified JSON, do not end up stored whole
+const MaxHeaderSize = 64 * 1024
+
+// sniffSize is the length of the beginning of a file checked for binary data
+const sniffSize = 8000
+
+// Header returns the first lines of a text file, at most MaxHeaderSize bytes of
+// them, or nil if the file looks binary
+func Header(r io.Reader, lines int) ([]byte, error) {
+	br := bufio.NewReaderSize(io.LimitReader(r, MaxHeaderSize), sniffSize)
+	head, err := br.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("failed to read file header: %w", err)
+	}
+	if textdiff.IsBinary(head) {
+		return nil, nil
+	}
+
+	var header bytes.Buffer
+	for i := 0; i < lines; i++ {
+		line, err := br.ReadBytes('\n')
+		header.Write(line)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file header: %w", err)
+		}
+	}
+	return header.Bytes(), nil
+}
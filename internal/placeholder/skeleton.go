@@ -0,0 +1,179 @@
+package placeholder
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GoSkeleton returns the skeleton of a Go source file: its package clause,
+// imports and declarations, with the body of every function replaced by
+// panic("TODO"). init functions get an empty body instead, so that importing
+// the package does not panic. Imports only used in function bodies are
+// dropped so that the skeleton compiles, and so are comments in the bodies.
+func GoSkeleton(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Go source: %w", err)
+	}
+
+	// Replace the function bodies, remembering where they were
+	var removed []span
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		removed = append(removed, spanOf(fn.Body))
+		fn.Body = todoBody(fn.Body.Lbrace, fn.Recv == nil && fn.Name.Name == "init")
+	}
+
+	// Drop the imports the remaining declarations do not use
+	used := usedPackageNames(file)
+	decls := file.Decls[:0]
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			decls = append(decls, decl)
+			continue
+		}
+
+		declSpan := spanOf(gen)
+		specs := gen.Specs[:0]
+		for _, spec := range gen.Specs {
+			if importUsed(spec.(*ast.ImportSpec), used) {
+				specs = append(specs, spec)
+				continue
+			}
+			removed = append(removed, spanOf(spec))
+
+			// Join the line of the import to the one above so that no gap is left,
+			// unless that line is outside the import block, as when the block is
+			// written on a single line
+			if gen.Lparen.IsValid() {
+				tokFile := fset.File(spec.Pos())
+				if line := tokFile.Line(spec.Pos()); line > tokFile.Line(gen.Lparen) {
+					tokFile.MergeLine(line - 1)
+				}
+			}
+		}
+		gen.Specs = specs
+		if len(specs) > 0 {
+			decls = append(decls, gen)
+		} else {
+			removed = append(removed, declSpan)
+		}
+	}
+	file.Decls = decls
+
+	// Drop the comments that were in removed code
+	comments := file.Comments[:0]
+	for _, group := range file.Comments {
+		if !within(group, removed) {
+			comments = append(comments, group)
+		}
+	}
+	file.Comments = comments
+
+	var out bytes.Buffer
+	if err := format.Node(&out, fset, file); err != nil {
+		return nil, fmt.Errorf("failed to print Go skeleton: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// todoBody returns a function body calling panic("TODO"), or an empty one,
+// placed at the opening brace of the body it replaces
+func todoBody(lbrace token.Pos, empty bool) *ast.BlockStmt {
+	body := &ast.BlockStmt{Lbrace: lbrace, Rbrace: lbrace + 1}
+	if !empty {
+		body.List = []ast.Stmt{&ast.ExprStmt{X: &ast.CallExpr{
+			Fun:    &ast.Ident{Name: "panic", NamePos: lbrace + 1},
+			Lparen: lbrace + 1,
+			Args:   []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote("TODO")}},
+		}}}
+	}
+	return body
+}
+
+// usedPackageNames returns the identifiers qualifying a selector outside the
+// import declarations, which include the names of the packages in use
+func usedPackageNames(file *ast.File) map[string]bool {
+	used := make(map[string]bool)
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			continue
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if ident, ok := sel.X.(*ast.Ident); ok {
+					used[ident.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	return used
+}
+
+// importUsed reports whether an import is still needed. Blank, dot and cgo
+// imports are always kept.
+func importUsed(spec *ast.ImportSpec, used map[string]bool) bool {
+	importPath, err := strconv.Unquote(spec.Path.Value)
+	if err != nil || importPath == "C" {
+		return true
+	}
+	if spec.Name != nil {
+		return spec.Name.Name == "_" || spec.Name.Name == "." || used[spec.Name.Name]
+	}
+	return used[assumedPackageName(importPath)]
+}
+
+// assumedPackageName guesses the name of a package from its import path the
+// way goimports does: the last element, skipping a major version suffix, with
+// a "go-" prefix and anything following the first invalid character removed
+func assumedPackageName(importPath string) string {
+	base := path.Base(importPath)
+	if strings.HasPrefix(base, "v") {
+		if _, err := strconv.Atoi(base[1:]); err == nil {
+			if dir := path.Dir(importPath); dir != "." {
+				base = path.Base(dir)
+			}
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+// span is the source range of a removed node
+type span struct {
+	pos, end token.Pos
+}
+
+// spanOf returns the source range of a node before it is changed
+func spanOf(node ast.Node) span {
+	return span{node.Pos(), node.End()}
+}
+
+// within reports whether a node lies inside any of the removed ranges
+func within(node ast.Node, removed []span) bool {
+	for _, r := range removed {
+		if node.Pos() >= r.pos && node.End() <= r.end {
+			return true
+		}
+	}
+	return false
+}
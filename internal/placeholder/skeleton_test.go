@@ -0,0 +1,77 @@
+package placeholder
+
+import "testing"
+
+func TestGoSkeleton(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "bodies replaced and unused imports dropped",
+			src: `package p
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// F does things
+func F(w io.Writer) error {
+	// write it
+	fmt.Fprintln(w)
+	os.Exit(1)
+	return nil
+}
+
+func init() { fmt.Println() }
+`,
+			want: `package p
+
+import (
+	"io"
+)
+
+// F does things
+func F(w io.Writer) error { panic("TODO") }
+
+func init() {}
+`,
+		},
+		{
+			name: "single import",
+			src:  "package p\nimport \"os\"\nfunc f() { os.Exit(1) }\n",
+			want: "package p\n\nfunc f() { panic(\"TODO\") }\n",
+		},
+		{
+			name: "import block on one line",
+			src:  `package p; import ("os"; "fmt"); func f(){os.Exit(1); fmt.Println()}`,
+			want: "package p\n\nfunc f() { panic(\"TODO\") }\n",
+		},
+		{
+			name: "import block on one line partly used",
+			src:  `package p; import ("fmt"; "io"); func f(w io.Writer){fmt.Fprintln(w)}`,
+			want: "package p\n\nimport (\n\t\"io\"\n)\n\nfunc f(w io.Writer) { panic(\"TODO\") }\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GoSkeleton([]byte(tt.src))
+			if err != nil {
+				t.Fatalf("GoSkeleton() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("GoSkeleton() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoSkeletonInvalidSource(t *testing.T) {
+	if _, err := GoSkeleton([]byte("package p\nfunc {")); err == nil {
+		t.Error("GoSkeleton() succeeded on invalid source")
+	}
+}
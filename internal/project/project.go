@@ -0,0 +1,62 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileName is the name of the file recording which template a project was restored from
+const FileName = ".tmpltr.json"
+
+// Record describes the template a project directory was created from, so that
+// later template versions can be applied to it
+type Record struct {
+	Template   string            `json:"template"`            // Name of the template
+	Version    int               `json:"version"`             // Template version the project currently matches
+	Variables  map[string]string `json:"variables,omitempty"` // Values of the non-sensitive variables used to render the template
+	RestoredAt time.Time         `json:"restored_at"`         // Timestamp of the restore or last update
+}
+
+// Path returns the path of the record file in a project directory
+func Path(dir string) string {
+	return filepath.Join(dir, FileName)
+}
+
+// Load reads the record of a project directory
+func Load(dir string) (*Record, error) {
+	data, err := os.ReadFile(Path(dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s was not restored from a template (no %s found)", dir, FileName)
+		}
+		return nil, fmt.Errorf("failed to read project record: %w", err)
+	}
+
+	var record Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse project record: %w", err)
+	}
+
+	if record.Template == "" || record.Version < 1 {
+		return nil, fmt.Errorf("project record %s is incomplete", Path(dir))
+	}
+
+	return &record, nil
+}
+
+// Save writes the record of a project directory
+func Save(dir string, record *Record) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal project record: %w", err)
+	}
+
+	if err := os.WriteFile(Path(dir), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write project record: %w", err)
+	}
+
+	return nil
+}
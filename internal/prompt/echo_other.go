@@ -0,0 +1,15 @@
+//go:build !unix
+
+package prompt
+
+import "os"
+
+// disableEcho is not supported on this platform, so input is echoed
+func disableEcho(terminal *os.File) (func(), error) {
+	return func() {}, nil
+}
+
+// isTerminal assumes that every character device is a terminal
+func isTerminal(f *os.File) bool {
+	return true
+}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package prompt
+
+import (
+	"os"
+	"os/exec"
+)
+
+// disableEcho turns off the echo of a terminal with stty and returns the
+// function turning it back on
+func disableEcho(terminal *os.File) (func(), error) {
+	if err := stty(terminal, "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(terminal, "echo") }, nil
+}
+
+// isTerminal reports whether a character device is a terminal, which stty
+// can read the settings of
+func isTerminal(f *os.File) bool {
+	return stty(f, "-g") == nil
+}
+
+// stty reads or changes the settings of a terminal
+func stty(terminal *os.File, setting string) error {
+	cmd := exec.Command("stty", setting)
+	cmd.Stdin = terminal
+	return cmd.Run()
+}
@@ -0,0 +1,52 @@
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// stdin buffers the input of all prompts, so that input read ahead by one
+// prompt, such as several lines piped at once, is left to the next ones
+var stdin = bufio.NewReader(os.Stdin)
+
+// IsTerminal reports whether a file is an interactive terminal, rather than
+// another character device such as /dev/null
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0 && isTerminal(f)
+}
+
+// ReadLine prints a prompt to stderr and reads a line from stdin
+func ReadLine(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	return readLine()
+}
+
+// ReadSecret prints a prompt to stderr and reads a line from stdin without
+// echoing it, where the terminal supports turning echo off
+func ReadSecret(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+
+	restore, err := disableEcho(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to disable terminal echo: %w", err)
+	}
+	line, err := readLine()
+	restore()
+
+	// The newline typed by the user was not echoed
+	fmt.Fprintln(os.Stderr)
+	return line, err
+}
+
+// readLine reads a line from stdin without its line ending
+func readLine() (string, error) {
+	line, err := stdin.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,152 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"tmpltr/internal/manifest"
+)
+
+// VariablePrefix starts the names of the variables replacing redacted secrets
+const VariablePrefix = "Secret_"
+
+// Redactor replaces secrets with placeholders of generated variables. The same
+// secret is given the same variable wherever it occurs, and different secrets
+// that would get the same name are numbered.
+type Redactor struct {
+	names     map[string]string // Variable name by secret
+	used      map[string]bool
+	variables []manifest.Variable
+	byPath    map[string][]string // Variable names by the path of the file last redacted there
+}
+
+// NewRedactor creates a Redactor without variables
+func NewRedactor() *Redactor {
+	return &Redactor{
+		names:  make(map[string]string),
+		used:   make(map[string]bool),
+		byPath: make(map[string][]string),
+	}
+}
+
+// Variables returns the variables of the secrets redacted from the files with
+// the given slash-separated paths, in the order they were first found. Files
+// redacted again replace what was redacted from them before, and variables of
+// files that are not given, such as files later left out, are not returned.
+func (rd *Redactor) Variables(paths []string) []manifest.Variable {
+	wanted := make(map[string]bool)
+	for _, p := range paths {
+		for _, name := range rd.byPath[p] {
+			wanted[name] = true
+		}
+	}
+
+	var variables []manifest.Variable
+	for _, v := range rd.variables {
+		if wanted[v.Name] {
+			variables = append(variables, v)
+		}
+	}
+	return variables
+}
+
+// Redact returns the content of a file with the secrets found in it by Scan
+// replaced by variable placeholders
+func (rd *Redactor) Redact(content []byte, findings []Finding) []byte {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+
+	// Name the variables in file order, then replace from the end so that the
+	// positions of earlier secrets stay valid
+	sorted := append([]Finding{}, findings...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Line != sorted[j].Line {
+			return sorted[i].Line < sorted[j].Line
+		}
+		return sorted[i].Start < sorted[j].Start
+	})
+	names := make([]string, len(sorted))
+	for i, f := range sorted {
+		names[i] = rd.variable(f)
+	}
+	if len(sorted) > 0 {
+		rd.byPath[sorted[0].Path] = names
+	}
+
+	for i := len(sorted) - 1; i >= 0; i-- {
+		f := sorted[i]
+		first, last := f.Line-1, f.EndLine-1
+		if last < first {
+			last = first
+		}
+		if last >= len(lines) {
+			continue
+		}
+
+		var line []byte
+		line = append(line, lines[first][:f.Start]...)
+		line = append(line, manifest.Placeholder(names[i])...)
+		line = append(line, lines[last][f.End:]...)
+		lines = append(lines[:first], append([][]byte{line}, lines[last+1:]...)...)
+	}
+
+	return bytes.Join(lines, nil)
+}
+
+// variable returns the name of the variable for a secret, registering it the
+// first time the secret is seen
+func (rd *Redactor) variable(f Finding) string {
+	if name, ok := rd.names[f.Value]; ok {
+		return name
+	}
+
+	base := VariablePrefix + variableBase(f)
+	name := base
+	for n := 2; rd.used[name]; n++ {
+		name = fmt.Sprintf("%s_%d", base, n)
+	}
+	rd.names[f.Value] = name
+	rd.used[name] = true
+	rd.variables = append(rd.variables, manifest.Variable{
+		Name:        name,
+		Description: fmt.Sprintf("%s redacted from %s:%d", f.Rule, f.Path, f.Line),
+		Required:    true,
+		Sensitive:   true,
+	})
+	return name
+}
+
+// variableBase derives the part of a variable name describing a secret: the
+// assigned variable for credential assignments and the kind of secret otherwise
+func variableBase(f Finding) string {
+	switch f.Rule {
+	case RuleAssignment:
+		return sanitizeName(f.Key)
+	case RulePrivateKey:
+		return "PRIVATE_KEY"
+	case RuleAWSKey:
+		return "AWS_ACCESS_KEY"
+	default:
+		return "TOKEN"
+	}
+}
+
+// sanitizeName upper-cases a name and replaces the characters not allowed in
+// variable names by underscores
+func sanitizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	if name == "" {
+		return "VALUE"
+	}
+	return name
+}
@@ -0,0 +1,290 @@
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"tmpltr/internal/ignore"
+)
+
+// AllowFileName is the name of the file listing patterns of files allowed to hold secrets
+const AllowFileName = ".tmpltrsecrets"
+
+// Names of the detection rules
+const (
+	RulePrivateKey = "private key"
+	RuleAWSKey     = "AWS access key"
+	RuleAssignment = "credential assignment"
+	RuleEntropy    = "high-entropy token"
+)
+
+// Thresholds of the high-entropy token rule
+const (
+	minTokenLength = 32
+	minEntropy     = 4.5 // Bits per character; hex digests stay below 4
+)
+
+var (
+	privateKeyPattern    = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+	privateKeyEndPattern = regexp.MustCompile(`-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+	awsKeyPattern        = regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA)[0-9A-Z]{16}\b`)
+
+	// assignmentPattern matches .env-style lines such as PASSWORD=... assigning a
+	// value to a credential-like variable; the variable is the first group and
+	// the value the second
+	assignmentPattern = regexp.MustCompile(`^\s*(?:export\s+)?([A-Za-z0-9_]*(?i:password|passwd|pwd|secret|token|api_?key|access_?key|private_?key|credentials?)[A-Za-z0-9_]*)=["']?([^"'\s#]+)`)
+
+	// tokenPattern matches candidate tokens for the entropy rule
+	tokenPattern = regexp.MustCompile(`[A-Za-z0-9+/_\-]{32,}={0,2}`)
+)
+
+// entropyExempt are files whose long tokens are checksums rather than secrets
+var entropyExempt = map[string]bool{
+	"go.sum":              true,
+	"package-lock.json":   true,
+	"npm-shrinkwrap.json": true,
+	"yarn.lock":           true,
+	"pnpm-lock.yaml":      true,
+	"Cargo.lock":          true,
+	"poetry.lock":         true,
+	"Pipfile.lock":        true,
+	"composer.lock":       true,
+	"Gemfile.lock":        true,
+}
+
+// placeholderValues are assignment values that are clearly not real credentials
+var placeholderValues = map[string]bool{
+	"changeme": true, "change_me": true, "password": true, "secret": true, "token": true,
+	"xxx": true, "none": true, "null": true, "nil": true, "true": true, "false": true,
+	"example": true, "placeholder": true, "redacted": true, "todo": true,
+}
+
+// Finding is a likely secret in a file
+type Finding struct {
+	Path    string // Slash-separated path of the file
+	Line    int    // Line number, starting at 1
+	EndLine int    // Line the secret ends on, after Line for private key blocks
+	Start   int    // Byte offset of the secret in the line
+	End     int    // Byte offset following the secret in its end line
+	Rule    string // Rule that detected the secret
+	Key     string // Variable assigned the secret, for credential assignments
+	Value   string // The secret itself
+}
+
+// maxMasked is the most masked characters shown for a secret, so that
+// private key blocks do not fill the screen
+const maxMasked = 32
+
+// Masked returns the secret with all but its first characters masked, for display
+func (f Finding) Masked() string {
+	visible := 4
+	if len(f.Value) <= 8 {
+		visible = 2
+	}
+	if len(f.Value) <= visible {
+		return strings.Repeat("*", len(f.Value))
+	}
+	return f.Value[:visible] + strings.Repeat("*", min(len(f.Value)-visible, maxMasked))
+}
+
+// Scan reads text from r and returns the likely secrets in it, naming the file
+// relPath in the findings. A private key extends to its END line, and the lines
+// of the key are not scanned otherwise. Binary content is not scanned.
+func Scan(relPath string, r io.Reader) ([]Finding, error) {
+	br := bufio.NewReaderSize(r, ignore.HeadSize)
+	head, err := br.Peek(ignore.HeadSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("failed to read %s: %w", relPath, err)
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+
+	checkEntropy := !entropyExempt[path.Base(relPath)]
+
+	var findings []Finding
+	openKey := -1 // Index of the private key whose END line is not read yet
+	for lineNum := 1; ; lineNum++ {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			text := strings.TrimRight(line, "\r\n")
+			if openKey >= 0 {
+				key := &findings[openKey]
+				key.EndLine, key.End = lineNum, len(text)
+				if loc := privateKeyEndPattern.FindStringIndex(text); loc != nil {
+					key.End = loc[1]
+					openKey = -1
+				}
+				key.Value += "\n" + text[:key.End]
+			} else {
+				for _, f := range scanLine(text, checkEntropy) {
+					f.Path, f.Line, f.EndLine = relPath, lineNum, lineNum
+					if f.Rule == RulePrivateKey && !privateKeyEndPattern.MatchString(f.Value) {
+						openKey = len(findings)
+					}
+					findings = append(findings, f)
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", relPath, err)
+		}
+	}
+	return findings, nil
+}
+
+// ScanFile scans a file on disk for likely secrets
+func ScanFile(filePath, relPath string) ([]Finding, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", relPath, err)
+	}
+	defer file.Close()
+
+	return Scan(relPath, file)
+}
+
+// scanLine returns the secrets in a line; a part of the line is only reported
+// by the first rule detecting it
+func scanLine(line string, checkEntropy bool) []Finding {
+	var findings []Finding
+	overlaps := func(start, end int) bool {
+		for _, f := range findings {
+			if start < f.End && end > f.Start {
+				return true
+			}
+		}
+		return false
+	}
+	add := func(start, end int, rule string) {
+		if !overlaps(start, end) {
+			findings = append(findings, Finding{Start: start, End: end, Rule: rule, Value: line[start:end]})
+		}
+	}
+
+	if loc := privateKeyPattern.FindStringIndex(line); loc != nil {
+		// A key on a single line extends to its END marker
+		end := loc[1]
+		if endLoc := privateKeyEndPattern.FindStringIndex(line[end:]); endLoc != nil {
+			end += endLoc[1]
+		}
+		add(loc[0], end, RulePrivateKey)
+	}
+	for _, loc := range awsKeyPattern.FindAllStringIndex(line, -1) {
+		add(loc[0], loc[1], RuleAWSKey)
+	}
+	if m := assignmentPattern.FindStringSubmatchIndex(line); m != nil {
+		if value := line[m[4]:m[5]]; isCredentialValue(value) && !overlaps(m[4], m[5]) {
+			findings = append(findings, Finding{Start: m[4], End: m[5], Rule: RuleAssignment, Key: line[m[2]:m[3]], Value: value})
+		}
+	}
+	if checkEntropy {
+		for _, loc := range tokenPattern.FindAllStringIndex(line, -1) {
+			if token := line[loc[0]:loc[1]]; isRandomToken(token) {
+				add(loc[0], loc[1], RuleEntropy)
+			}
+		}
+	}
+	return findings
+}
+
+// isCredentialValue reports whether an assigned value looks like a real credential
+// rather than a placeholder or a reference to another variable
+func isCredentialValue(value string) bool {
+	if len(value) < 4 || placeholderValues[strings.ToLower(value)] {
+		return false
+	}
+	if strings.ContainsAny(value[:1], "$<{%") || strings.Contains(value, "{{") {
+		return false
+	}
+	return strings.Trim(value, "*x.X") != ""
+}
+
+// isRandomToken reports whether a token mixes letters and digits with the
+// entropy of random data
+func isRandomToken(token string) bool {
+	if len(token) < minTokenLength {
+		return false
+	}
+	hasLetter := strings.IndexFunc(token, func(r rune) bool { return r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' }) >= 0
+	hasDigit := strings.IndexFunc(token, func(r rune) bool { return r >= '0' && r <= '9' }) >= 0
+	return hasLetter && hasDigit && entropy(token) >= minEntropy
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	counts := make(map[byte]int)
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+
+	var bits float64
+	for _, count := range counts {
+		p := float64(count) / float64(len(s))
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
+
+// Allowlist holds the patterns of files allowed to contain secrets
+type Allowlist struct {
+	patterns []ignore.Pattern
+}
+
+// LoadAllowlist reads an allowlist file of .gitignore-style patterns relative to
+// the directory the file is in. A missing file allows nothing.
+func LoadAllowlist(filePath string) (*Allowlist, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Allowlist{}, nil
+		}
+		return nil, fmt.Errorf("failed to open secrets allowlist: %w", err)
+	}
+	defer file.Close()
+
+	return ReadAllowlist(file)
+}
+
+// ReadAllowlist reads an allowlist in the format of the allowlist file from r
+func ReadAllowlist(r io.Reader) (*Allowlist, error) {
+	al := &Allowlist{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if p, ok := ignore.ParsePattern(scanner.Text(), ""); ok {
+			al.patterns = append(al.patterns, p)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read secrets allowlist: %w", err)
+	}
+	return al, nil
+}
+
+// Allows reports whether a file at a slash-separated path may contain secrets.
+// Patterns match the file or one of its parent directories, and the last
+// matching pattern wins.
+func (al *Allowlist) Allows(relPath string) bool {
+	for i := len(al.patterns) - 1; i >= 0; i-- {
+		p := &al.patterns[i]
+		if p.Match(relPath, false) {
+			return !p.Negated()
+		}
+		for dir := path.Dir(relPath); dir != "."; dir = path.Dir(dir) {
+			if p.Match(dir, true) {
+				return !p.Negated()
+			}
+		}
+	}
+	return false
+}
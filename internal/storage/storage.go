@@ -5,7 +5,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
+	"tmpltr/internal/cache"
 	"tmpltr/internal/compression"
+	"tmpltr/internal/config"
+	"tmpltr/internal/hash"
 	"tmpltr/internal/manifest"
 )
 
@@ -13,11 +17,23 @@ const (
 	DefaultTemplateDir = ".tmpltr/templates"
 	ManifestFileName   = "manifest.json"
 	FilesSubDir        = "files"
+	CacheDir           = "cache"
 )
 
 // Storage manages template storage operations
 type Storage struct {
 	baseDir string
+
+	mu    sync.Mutex
+	blobs map[string]BlobInfo // blobs known to be stored, keyed by template name and hash
+}
+
+// BlobInfo describes a file content blob stored in a template's files directory
+type BlobInfo struct {
+	Hash         string // SHA256 hash of the original content
+	Compressed   bool   // Whether the blob is stored gzip-compressed
+	OriginalSize int64  // Size of the original content in bytes
+	StoredSize   int64  // Size of the blob on disk in bytes
 }
 
 // NewStorage creates a new Storage instance with the given base directory
@@ -31,7 +47,22 @@ func NewStorage(baseDir string) (*Storage, error) {
 		baseDir = filepath.Join(homeDir, DefaultTemplateDir)
 	}
 
-	return &Storage{baseDir: baseDir}, nil
+	return &Storage{
+		baseDir: baseDir,
+		blobs:   make(map[string]BlobInfo),
+	}, nil
+}
+
+// GetConfigPath returns the full path to the configuration file, which lives next
+// to the templates directory
+func (s *Storage) GetConfigPath() string {
+	return filepath.Join(filepath.Dir(s.baseDir), config.FileName)
+}
+
+// GetCachePath returns the full path to the hash cache file, which lives next to
+// the templates directory
+func (s *Storage) GetCachePath() string {
+	return filepath.Join(filepath.Dir(s.baseDir), CacheDir, cache.FileName)
 }
 
 // GetTemplatePath returns the full path to a template directory
@@ -123,6 +154,242 @@ func (s *Storage) SaveFileWithCompression(templateName, hash, originalPath strin
 	return shouldCompress, int64(len(finalContent)), nil
 }
 
+// SaveStream streams content from r into the template's file store. The content is
+// hashed, optionally compressed and written in a single pass, so memory use does not
+// depend on the size of the content. If compression does not reduce the size, the
+// blob is stored uncompressed instead. Blobs that are already stored are not duplicated.
+func (s *Storage) SaveStream(templateName string, r io.Reader, compress bool) (BlobInfo, error) {
+	tmpFile, err := os.CreateTemp(s.GetFilesPath(templateName), ".tmp-*")
+	if err != nil {
+		return BlobInfo{}, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	info, err := writeBlob(tmpFile, r, compress)
+	if err == nil {
+		err = tmpFile.Chmod(0644)
+	}
+	if closeErr := tmpFile.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close temporary file: %w", closeErr)
+	}
+	if err != nil {
+		return BlobInfo{}, err
+	}
+
+	return s.commitBlob(templateName, tmpPath, info)
+}
+
+// writeBlob copies r into f, hashing the original content and compressing it if requested
+func writeBlob(f *os.File, r io.Reader, compress bool) (BlobInfo, error) {
+	hasher := hash.NewHasher()
+	src := io.TeeReader(r, hasher)
+
+	var info BlobInfo
+	var err error
+	if compress {
+		info.OriginalSize, err = compression.CompressStream(f, src)
+	} else {
+		info.OriginalSize, err = io.Copy(f, src)
+	}
+	if err != nil {
+		return BlobInfo{}, fmt.Errorf("failed to write content: %w", err)
+	}
+
+	info.Hash = hash.HexSum(hasher)
+	info.Compressed = compress
+
+	stat, err := f.Stat()
+	if err != nil {
+		return BlobInfo{}, fmt.Errorf("failed to stat temporary file: %w", err)
+	}
+	info.StoredSize = stat.Size()
+
+	// Only keep compression if it actually reduces size
+	if compress && info.StoredSize >= info.OriginalSize {
+		if err := decompressInPlace(f); err != nil {
+			return BlobInfo{}, err
+		}
+		info.Compressed = false
+		info.StoredSize = info.OriginalSize
+	}
+
+	return info, nil
+}
+
+// decompressInPlace replaces the gzip content of f with its decompressed form.
+// The compressed data is streamed through a sibling temporary file, so this never
+// needs to hold the content in memory or re-read the original source.
+func decompressInPlace(f *os.File) error {
+	plain, err := os.CreateTemp(filepath.Dir(f.Name()), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(plain.Name())
+	defer plain.Close()
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind temporary file: %w", err)
+	}
+	if _, err := compression.DecompressStream(plain, f); err != nil {
+		return err
+	}
+
+	if _, err := plain.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind temporary file: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind temporary file: %w", err)
+	}
+	n, err := io.Copy(f, plain)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite uncompressed content: %w", err)
+	}
+
+	return f.Truncate(n)
+}
+
+// commitBlob moves a fully written temporary blob to its content-addressed location.
+// If the same blob was already stored, the temporary file is discarded and the
+// information of the existing blob is returned.
+func (s *Storage) commitBlob(templateName, tmpPath string, info BlobInfo) (BlobInfo, error) {
+	key := blobKey(templateName, info.Hash)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.blobs[key]; ok {
+		return existing, nil
+	}
+
+	// A blob on disk that we have no information about may be referenced by older
+	// versions with its own compression state, so it is kept as it is. Only a blob
+	// whose content does not match its hash is replaced.
+	blobPath := s.GetFileContentPath(templateName, info.Hash)
+	if existing, ok := inspectBlob(blobPath, info); ok {
+		s.blobs[key] = existing
+		return existing, nil
+	}
+
+	if err := os.Rename(tmpPath, blobPath); err != nil {
+		return BlobInfo{}, fmt.Errorf("failed to save file with hash %s: %w", info.Hash, err)
+	}
+
+	s.blobs[key] = info
+	return info, nil
+}
+
+// inspectBlob reports the actual state of a blob stored at blobPath holding the
+// content described by info, by checking whether its raw or decompressed content
+// has the expected hash
+func inspectBlob(blobPath string, info BlobInfo) (BlobInfo, bool) {
+	stat, err := os.Stat(blobPath)
+	if err != nil || !stat.Mode().IsRegular() {
+		return BlobInfo{}, false
+	}
+
+	existing := BlobInfo{Hash: info.Hash, OriginalSize: info.OriginalSize, StoredSize: stat.Size()}
+	if blobHash(blobPath, false) == info.Hash {
+		return existing, true
+	}
+	if blobHash(blobPath, true) == info.Hash {
+		existing.Compressed = true
+		return existing, true
+	}
+	return BlobInfo{}, false
+}
+
+// blobHash returns the hash of the content of a stored blob, decompressing it
+// first if requested. An empty hash is returned if the blob cannot be read.
+func blobHash(blobPath string, compressed bool) string {
+	f, err := os.Open(blobPath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	hasher := hash.NewHasher()
+	if compressed {
+		_, err = compression.DecompressStream(hasher, f)
+	} else {
+		_, err = io.Copy(hasher, f)
+	}
+	if err != nil {
+		return ""
+	}
+	return hash.HexSum(hasher)
+}
+
+// RegisterBlobs records the blobs referenced by an existing manifest of the template,
+// so that saving the same content again reuses them instead of rewriting them
+func (s *Storage) RegisterBlobs(templateName string, m *manifest.Manifest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, file := range m.Files {
+		if !file.IncludeContents {
+			continue
+		}
+		s.blobs[blobKey(templateName, file.Hash)] = BlobInfo{
+			Hash:         file.Hash,
+			Compressed:   file.Compressed,
+			OriginalSize: file.OriginalSize,
+			StoredSize:   file.StoredSize,
+		}
+	}
+}
+
+// LookupBlob returns the information of a blob known to be stored for the template,
+// either because it was saved by this Storage or registered from a manifest
+func (s *Storage) LookupBlob(templateName, hash string) (BlobInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, ok := s.blobs[blobKey(templateName, hash)]
+	return info, ok
+}
+
+// blobKey returns the key identifying a blob of a template in the blob index
+func blobKey(templateName, hash string) string {
+	return templateName + "/" + hash
+}
+
+// OpenFile opens a stored file by hash for streaming, transparently decompressing it
+// if needed. The caller must close the returned reader.
+func (s *Storage) OpenFile(templateName, hash string, isCompressed bool) (io.ReadCloser, error) {
+	file, err := os.Open(s.GetFileContentPath(templateName, hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load file with hash %s: %w", hash, err)
+	}
+
+	if !isCompressed {
+		return file, nil
+	}
+
+	reader, err := compression.NewDecompressReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to decompress file with hash %s: %w", hash, err)
+	}
+
+	return &decompressingFile{ReadCloser: reader, file: file}, nil
+}
+
+// decompressingFile closes both the decompressor and the underlying file
+type decompressingFile struct {
+	io.ReadCloser
+	file *os.File
+}
+
+// Close closes the decompressor and the underlying file
+func (d *decompressingFile) Close() error {
+	err := d.ReadCloser.Close()
+	if fileErr := d.file.Close(); err == nil {
+		err = fileErr
+	}
+	return err
+}
+
 // LoadFile loads file content from storage by hash
 func (s *Storage) LoadFile(templateName, hash string) ([]byte, error) {
 	filePath := s.GetFileContentPath(templateName, hash)
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tmpltr/internal/manifest"
+)
+
+const (
+	VersionsSubDir = "versions"
+
+	// RefSeparator separates a template name from a version selector, e.g. "svc@v2"
+	RefSeparator = "@"
+
+	// dateLayout is the layout of date version selectors, e.g. "svc@2026-09-01"
+	dateLayout = "2006-01-02"
+)
+
+// GetVersionsPath returns the full path to a template's versions directory
+func (s *Storage) GetVersionsPath(templateName string) string {
+	return filepath.Join(s.GetTemplatePath(templateName), VersionsSubDir)
+}
+
+// GetVersionPath returns the full path to the manifest of a template version
+func (s *Storage) GetVersionPath(templateName string, version int) string {
+	return filepath.Join(s.GetVersionsPath(templateName), strconv.Itoa(version)+".json")
+}
+
+// ParseTemplateRef splits a template reference such as "svc@v2" into the template
+// name and the version selector, which is empty if the reference has none
+func ParseTemplateRef(ref string) (name, selector string) {
+	name, selector, _ = strings.Cut(ref, RefSeparator)
+	return name, selector
+}
+
+// SaveVersion saves a manifest as a new immutable version of the template and makes
+// it the latest version. The version number is assigned here; the tag, if any, must
+// not be used by another version of the template.
+func (s *Storage) SaveVersion(templateName string, m *manifest.Manifest) error {
+	versions, err := s.ListVersions(templateName)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.GetVersionsPath(templateName), 0755); err != nil {
+		return fmt.Errorf("failed to create versions directory: %w", err)
+	}
+
+	next := 1
+	for _, v := range versions {
+		if m.Tag != "" && v.Tag == m.Tag {
+			return fmt.Errorf("tag '%s' is already used by version %d", m.Tag, v.Version)
+		}
+		next = v.Version + 1
+
+		// Templates created before versioning only have a latest manifest,
+		// which is kept as their first version
+		if !fileExists(s.GetVersionPath(templateName, v.Version)) {
+			if err := s.writeVersion(templateName, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	m.Version = next
+	if err := s.writeVersion(templateName, m); err != nil {
+		return err
+	}
+
+	return s.SaveManifest(templateName, m)
+}
+
+// writeVersion writes the manifest of a version without replacing an existing one,
+// so that versions stay immutable even if two updates race
+func (s *Storage) writeVersion(templateName string, m *manifest.Manifest) error {
+	versionPath := s.GetVersionPath(templateName, m.Version)
+	tmpPath := fmt.Sprintf("%s.%d.tmp", versionPath, os.Getpid())
+
+	if err := manifest.SaveManifest(m, tmpPath); err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	if err := os.Link(tmpPath, versionPath); err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("version %d of template '%s' already exists", m.Version, templateName)
+		}
+		return fmt.Errorf("failed to save version %d: %w", m.Version, err)
+	}
+
+	return nil
+}
+
+// ListVersions returns all versions of a template ordered from oldest to newest.
+// A template created before versioning is reported as a single version 1.
+func (s *Storage) ListVersions(templateName string) ([]*manifest.Manifest, error) {
+	entries, err := os.ReadDir(s.GetVersionsPath(templateName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read versions directory: %w", err)
+	}
+
+	var versions []*manifest.Manifest
+	for _, entry := range entries {
+		number, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		if _, err := strconv.Atoi(number); err != nil {
+			continue
+		}
+
+		m, err := manifest.LoadManifest(filepath.Join(s.GetVersionsPath(templateName), entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load version %s: %w", number, err)
+		}
+		versions = append(versions, m)
+	}
+
+	if len(versions) == 0 && s.TemplateExists(templateName) {
+		m, err := s.LoadManifest(templateName)
+		if err != nil {
+			return nil, err
+		}
+		if m.Version == 0 {
+			m.Version = 1
+		}
+		versions = append(versions, m)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+
+	return versions, nil
+}
+
+// LoadVersion loads the version of a template matching the selector. An empty
+// selector selects the latest version. Otherwise the selector is matched against
+// version tags, then version numbers ("2" or "v2"), and finally dates ("2026-09-01"),
+// which select the latest version created on or before that day.
+func (s *Storage) LoadVersion(templateName, selector string) (*manifest.Manifest, error) {
+	if !s.TemplateExists(templateName) {
+		return nil, fmt.Errorf("template '%s' does not exist", templateName)
+	}
+
+	if selector == "" {
+		m, err := s.LoadManifest(templateName)
+		if err != nil {
+			return nil, err
+		}
+		if m.Version == 0 {
+			m.Version = 1
+		}
+		return m, nil
+	}
+
+	versions, err := s.ListVersions(templateName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range versions {
+		if v.Tag == selector {
+			return v, nil
+		}
+	}
+
+	if number, err := strconv.Atoi(strings.TrimPrefix(selector, "v")); err == nil {
+		for _, v := range versions {
+			if v.Version == number {
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("template '%s' has no version %d", templateName, number)
+	}
+
+	if day, err := time.Parse(dateLayout, selector); err == nil {
+		end := day.AddDate(0, 0, 1)
+		var selected *manifest.Manifest
+		for _, v := range versions {
+			if v.VersionTime().Before(end) {
+				selected = v
+			}
+		}
+		if selected == nil {
+			return nil, fmt.Errorf("template '%s' has no version created on or before %s", templateName, selector)
+		}
+		return selected, nil
+	}
+
+	return nil, fmt.Errorf("template '%s' has no version matching '%s'", templateName, selector)
+}
+
+// LoadTemplateRef loads the manifest selected by a template reference such as
+// "svc", "svc@v2" or "svc@2026-09-01" and returns it with the template name
+func (s *Storage) LoadTemplateRef(ref string) (string, *manifest.Manifest, error) {
+	name, selector := ParseTemplateRef(ref)
+	m, err := s.LoadVersion(name, selector)
+	if err != nil {
+		return "", nil, err
+	}
+	return name, m, nil
+}
+
+// fileExists reports whether a file exists at path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,110 @@
+package textdiff
+
+import "strings"
+
+// Conflict marker lines written by Merge, as used by git and diff3
+const (
+	markerOurs   = "<<<<<<<"
+	markerSep    = "======="
+	markerTheirs = ">>>>>>>"
+)
+
+// Merge performs a three-way merge of two texts derived from a common base.
+// Changes made on only one side are applied; where both sides changed the same
+// region differently, the result contains conflict markers labelled with
+// oursLabel and theirsLabel. It returns the merged text and the number of conflicts.
+func Merge(base, ours, theirs, oursLabel, theirsLabel string) (string, int) {
+	b, o, t := SplitLines(base), SplitLines(ours), SplitLines(theirs)
+	toOurs := matches(Diff(b, o), len(b))
+	toTheirs := matches(Diff(b, t), len(b))
+
+	var out strings.Builder
+	conflicts := 0
+	i, oi, ti := 0, 0, 0
+	for {
+		// Copy lines unchanged on both sides
+		for i < len(b) && toOurs[i] == oi && toTheirs[i] == ti {
+			out.WriteString(b[i])
+			i, oi, ti = i+1, oi+1, ti+1
+		}
+
+		// Find the next base line kept by both sides, or the end of all inputs
+		j := i
+		for j < len(b) && (toOurs[j] < 0 || toTheirs[j] < 0) {
+			j++
+		}
+		oEnd, tEnd := len(o), len(t)
+		if j < len(b) {
+			oEnd, tEnd = toOurs[j], toTheirs[j]
+		}
+
+		if i == j && oi == oEnd && ti == tEnd {
+			break
+		}
+
+		baseChunk, oursChunk, theirsChunk := b[i:j], o[oi:oEnd], t[ti:tEnd]
+		switch {
+		case equalLines(oursChunk, baseChunk):
+			writeLines(&out, theirsChunk)
+		case equalLines(theirsChunk, baseChunk), equalLines(oursChunk, theirsChunk):
+			writeLines(&out, oursChunk)
+		default:
+			conflicts++
+			out.WriteString(markerOurs + " " + oursLabel + "\n")
+			writeTerminatedLines(&out, oursChunk)
+			out.WriteString(markerSep + "\n")
+			writeTerminatedLines(&out, theirsChunk)
+			out.WriteString(markerTheirs + " " + theirsLabel + "\n")
+		}
+
+		i, oi, ti = j, oEnd, tEnd
+	}
+
+	return out.String(), conflicts
+}
+
+// matches maps each line of the old input of an edit script to the index of the
+// line it is kept as in the new input, or -1 if it is deleted
+func matches(ops []Op, n int) []int {
+	m := make([]int, n)
+	for i := range m {
+		m[i] = -1
+	}
+	for _, op := range ops {
+		if op.Kind == OpEqual {
+			m[op.A] = op.B
+		}
+	}
+	return m
+}
+
+// equalLines reports whether two line slices are identical
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeLines writes lines unchanged
+func writeLines(out *strings.Builder, lines []string) {
+	for _, line := range lines {
+		out.WriteString(line)
+	}
+}
+
+// writeTerminatedLines writes lines, terminating the last one so that a
+// following conflict marker starts on its own line
+func writeTerminatedLines(out *strings.Builder, lines []string) {
+	for _, line := range lines {
+		out.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			out.WriteString("\n")
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package textdiff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change
+const DefaultContext = 3
+
+// minCostLimit is the least number of steps the middle snake of a diff is
+// searched for before settling for a longer edit script
+const minCostLimit = 256
+
+// binarySniffLen is how many leading bytes are inspected to detect binary content
+const binarySniffLen = 8000
+
+// OpKind is the kind of a diff operation
+type OpKind int
+
+const (
+	OpEqual  OpKind = iota // Line is present in both inputs
+	OpDelete               // Line is only present in the old input
+	OpInsert               // Line is only present in the new input
+)
+
+// Op is a single line of an edit script turning one list of lines into another
+type Op struct {
+	Kind OpKind
+	A    int // Index of the line in the old input, -1 for inserts
+	B    int // Index of the line in the new input, -1 for deletes
+}
+
+// IsBinary reports whether content looks like binary data rather than text
+func IsBinary(content []byte) bool {
+	if len(content) > binarySniffLen {
+		content = content[:binarySniffLen]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// SplitLines splits text into lines, keeping the line terminators so that a
+// missing newline at the end of the text is preserved
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Diff computes a shortest edit script turning a into b using Myers' algorithm in
+// its linear space form: the middle snake of an optimal path splits the inputs in
+// two halves that are diffed recursively, so memory use stays proportional to the
+// size of the inputs however much they differ.
+func Diff(a, b []string) []Op {
+	d := differ{a: a, b: b, ops: make([]Op, 0, len(a)+len(b))}
+	d.compare(0, len(a), 0, len(b))
+	return d.ops
+}
+
+// differ accumulates the edit script of a Diff
+type differ struct {
+	a, b []string
+	ops  []Op
+}
+
+// compare appends the edit script turning a[aLo:aHi] into b[bLo:bHi]
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.ops = append(d.ops, Op{Kind: OpEqual, A: aLo, B: bLo})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	if x, y, ok := d.split(aLo, aHi, bLo, bHi); ok {
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi, y, bHi)
+	} else {
+		for i := aLo; i < aHi; i++ {
+			d.ops = append(d.ops, Op{Kind: OpDelete, A: i, B: -1})
+		}
+		for j := bLo; j < bHi; j++ {
+			d.ops = append(d.ops, Op{Kind: OpInsert, A: -1, B: j})
+		}
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.ops = append(d.ops, Op{Kind: OpEqual, A: aHi + i, B: bHi + i})
+	}
+}
+
+// split finds the middle snake of a shortest edit script turning a[aLo:aHi] into
+// b[bLo:bHi], which must neither start nor end with a common line, and returns a
+// point of it. It reports false if the inputs have no line in common, in which
+// case all of one is deleted and all of the other inserted.
+func (d *differ) split(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	n, m := aHi-aLo, bHi-bLo
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+
+	// vf[k+offset] holds the furthest x reached on diagonal k searching forward
+	// from the start, vb[k+offset] the same searching backward from the end
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	vf := make([]int, 2*maxD+1)
+	vb := make([]int, 2*maxD+1)
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[offset+1], vb[offset+1] = 0, 0
+
+	// Diagonals of the backward search are shifted by delta relative to the
+	// forward one; the searches meet on the forward step when delta is odd and
+	// on the backward step when it is even
+	delta := n - m
+	front := delta%2 != 0
+
+	// Bounds trimming the diagonals that have run off the edges
+	var fStart, fEnd, bStart, bEnd int
+	for step := 0; step < maxD; step++ {
+		for k := -step + fStart; k <= step-fEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || (k != step && vf[i-1] < vf[i+1]) {
+				x = vf[i+1]
+			} else {
+				x = vf[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			vf[i] = x
+
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case front:
+				j := offset + delta - k
+				if j >= 0 && j < len(vb) && vb[j] != -1 && x >= n-vb[j] {
+					return aLo + x, bLo + y, true
+				}
+			}
+		}
+
+		for k := -step + bStart; k <= step-bEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || (k != step && vb[i-1] < vb[i+1]) {
+				x = vb[i+1]
+			} else {
+				x = vb[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aHi-x-1] == d.b[bHi-y-1] {
+				x++
+				y++
+			}
+			vb[i] = x
+
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !front:
+				j := offset + delta - k
+				if j >= 0 && j < len(vf) && vf[j] != -1 && vf[j] >= n-x {
+					fx := vf[j]
+					return aLo + fx, bLo + fx - (j - offset), true
+				}
+			}
+		}
+
+		// Searching for the shortest script of inputs that differ a lot is slow,
+		// so past a cost, split at the furthest point reached instead, at the
+		// price of a longer script
+		if step+1 >= costLimit(n+m) {
+			x, y := d.furthest(vf, vb, offset, step, fStart, fEnd, bStart, bEnd, n, m)
+			if (x > 0 || y > 0) && (x < n || y < m) {
+				return aLo + x, bLo + y, true
+			}
+			break
+		}
+	}
+
+	return 0, 0, false
+}
+
+// costLimit returns the number of steps the middle snake of inputs of size lines
+// is searched for before settling for a longer edit script
+func costLimit(size int) int {
+	limit := 1
+	for limit*limit < size {
+		limit++
+	}
+	return max(limit, minCostLimit)
+}
+
+// furthest returns the point reached by the forward or backward search at the
+// given step that is the furthest from where that search started
+func (d *differ) furthest(vf, vb []int, offset, step, fStart, fEnd, bStart, bEnd, n, m int) (int, int) {
+	bestX, bestY, best := 0, 0, -1
+	for k := -step + fStart; k <= step-fEnd; k += 2 {
+		x := vf[offset+k]
+		if y := x - k; x >= 0 && x <= n && y >= 0 && y <= m && x+y > best {
+			bestX, bestY, best = x, y, x+y
+		}
+	}
+	for k := -step + bStart; k <= step-bEnd; k += 2 {
+		x := vb[offset+k]
+		if y := x - k; x >= 0 && x <= n && y >= 0 && y <= m && x+y > best {
+			bestX, bestY, best = n-x, m-y, x+y
+		}
+	}
+	return bestX, bestY
+}
+
+// Unified returns a unified diff of two texts with the given file labels, or an
+// empty string if the texts are equal
+func Unified(oldLabel, newLabel, oldText, newText string, context int) string {
+	a, b := SplitLines(oldText), SplitLines(newText)
+	ops := Diff(a, b)
+
+	// aPos[i] and bPos[i] count the lines of each input preceding ops[i]
+	aPos, bPos := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, op := range ops {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		if op.A >= 0 {
+			aPos[i+1]++
+		}
+		if op.B >= 0 {
+			bPos[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change
+		for start < len(ops) && ops[start].Kind == OpEqual {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// Extend the hunk while changes are within 2*context lines of each other
+		hunkStart := max(start-context, 0)
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].Kind != OpEqual {
+				end = i + 1
+			} else if i-end >= 2*context {
+				break
+			}
+		}
+		hunkEnd := min(end+context, len(ops))
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldLabel, newLabel)
+		}
+		writeHunk(&out, a, b, ops[hunkStart:hunkEnd], aPos[hunkStart], bPos[hunkStart])
+		start = hunkEnd
+	}
+
+	return out.String()
+}
+
+// writeHunk writes a single hunk of a unified diff. aStart and bStart are the
+// number of lines of each input that precede the hunk.
+func writeHunk(out *strings.Builder, a, b []string, ops []Op, aStart, bStart int) {
+	aCount, bCount := 0, 0
+	for _, op := range ops {
+		if op.A >= 0 {
+			aCount++
+		}
+		if op.B >= 0 {
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, op := range ops {
+		switch op.Kind {
+		case OpEqual:
+			writeLine(out, " ", a[op.A])
+		case OpDelete:
+			writeLine(out, "-", a[op.A])
+		case OpInsert:
+			writeLine(out, "+", b[op.B])
+		}
+	}
+}
+
+// hunkRange formats the line range of one side of a hunk as in diff -u, where
+// an empty range refers to the line before the hunk
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+// writeLine writes a prefixed diff line, marking a missing final newline
+func writeLine(out *strings.Builder, prefix, line string) {
+	out.WriteString(prefix)
+	out.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		out.WriteString("\n\\ No newline at end of file\n")
+	}
+}
@@ -0,0 +1,132 @@
+package textdiff
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// checkScript fails the test unless ops turns a into b, and returns the number
+// of inserted and deleted lines
+func checkScript(t *testing.T, a, b []string, ops []Op) int {
+	t.Helper()
+	i, j, edits := 0, 0, 0
+	for _, op := range ops {
+		switch op.Kind {
+		case OpEqual:
+			if op.A != i || op.B != j || a[i] != b[j] {
+				t.Fatalf("invalid equal op %+v at a[%d], b[%d]", op, i, j)
+			}
+			i++
+			j++
+		case OpDelete:
+			if op.A != i {
+				t.Fatalf("invalid delete op %+v at a[%d]", op, i)
+			}
+			i++
+			edits++
+		case OpInsert:
+			if op.B != j {
+				t.Fatalf("invalid insert op %+v at b[%d]", op, j)
+			}
+			j++
+			edits++
+		}
+	}
+	if i != len(a) || j != len(b) {
+		t.Fatalf("script stops at a[%d], b[%d] of %d and %d lines", i, j, len(a), len(b))
+	}
+	return edits
+}
+
+// editDistance returns the number of lines inserted and deleted by a shortest
+// edit script, computed from the longest common subsequence
+func editDistance(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return len(a) + len(b) - 2*lcs[0][0]
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+	}{
+		{"both empty", nil, nil},
+		{"insert all", nil, []string{"a", "b"}},
+		{"delete all", []string{"a", "b"}, nil},
+		{"equal", []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+		{"nothing in common", []string{"a", "b"}, []string{"c", "d", "e"}},
+		{"change in the middle", []string{"a", "b", "c"}, []string{"a", "x", "c"}},
+		{"contained", []string{"b"}, []string{"a", "b", "c"}},
+		{"moved line", []string{"a", "b", "c", "d"}, []string{"b", "c", "d", "a"}},
+		{"repeated lines", []string{"a", "b", "a", "b", "a"}, []string{"b", "a", "b", "a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits := checkScript(t, tt.a, tt.b, Diff(tt.a, tt.b))
+			if want := editDistance(tt.a, tt.b); edits != want {
+				t.Errorf("Diff() has %d edits, want %d", edits, want)
+			}
+		})
+	}
+}
+
+func TestDiffRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	lines := func() []string {
+		out := make([]string, rng.Intn(30))
+		for i := range out {
+			out[i] = fmt.Sprint(rng.Intn(4))
+		}
+		return out
+	}
+
+	for n := 0; n < 500; n++ {
+		a, b := lines(), lines()
+		edits := checkScript(t, a, b, Diff(a, b))
+		if want := editDistance(a, b); edits != want {
+			t.Fatalf("Diff(%q, %q) has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestDiffLargeRewrite(t *testing.T) {
+	a, b := make([]string, 20000), make([]string, 20000)
+	for i := range a {
+		a[i] = fmt.Sprintf("old %d\n", i)
+		b[i] = fmt.Sprintf("new %d\n", i)
+	}
+
+	if edits := checkScript(t, a, b, Diff(a, b)); edits != len(a)+len(b) {
+		t.Errorf("Diff() has %d edits, want %d", edits, len(a)+len(b))
+	}
+}
+
+func TestDiffLargeRewriteSharingLines(t *testing.T) {
+	a, b := make([]string, 20000), make([]string, 20000)
+	for i := range a {
+		a[i] = fmt.Sprintf("old %d\n", i)
+		b[i] = fmt.Sprintf("new %d\n", i)
+		if i%5 == 0 {
+			a[i], b[i] = "\n", "}\n"
+		}
+		if i%7 == 0 {
+			a[i], b[i] = "}\n", "\n"
+		}
+	}
+
+	checkScript(t, a, b, Diff(a, b))
+}
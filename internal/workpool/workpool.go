@@ -0,0 +1,60 @@
+package workpool
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Run calls fn for every index in [0, count) using up to jobs concurrent workers.
+// Results should be written by fn into a slot of a pre-sized slice so that callers
+// keep a deterministic order regardless of scheduling. Once a call fails no further
+// indexes are started, and the error of the lowest failing index is returned.
+func Run(count, jobs int, fn func(i int) error) error {
+	if jobs < 1 {
+		return fmt.Errorf("number of jobs must be at least 1, got %d", jobs)
+	}
+	if jobs > count {
+		jobs = count
+	}
+
+	var (
+		mu       sync.Mutex
+		next     int
+		failed   bool
+		firstErr error
+		errIndex = count
+		wg       sync.WaitGroup
+	)
+
+	worker := func() {
+		defer wg.Done()
+		for {
+			mu.Lock()
+			if failed || next >= count {
+				mu.Unlock()
+				return
+			}
+			i := next
+			next++
+			mu.Unlock()
+
+			if err := fn(i); err != nil {
+				mu.Lock()
+				failed = true
+				if i < errIndex {
+					errIndex = i
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}
+	}
+
+	wg.Add(jobs)
+	for w := 0; w < jobs; w++ {
+		go worker()
+	}
+	wg.Wait()
+
+	return firstErr
+}